@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
@@ -24,8 +25,37 @@ func main() {
 		port = "8082"
 	}
 
-	// Create delivery instance
-	d := delivery.NewDelivery()
+	// Delivery times are estimated from the distance between the store and the customer
+	model := delivery.DefaultDeliveryModel()
+	model.Store.Lat = envFloat("STORE_LAT", model.Store.Lat)
+	model.Store.Lon = envFloat("STORE_LON", model.Store.Lon)
+
+	// Load delivery zones from a JSON file when one is configured
+	zones := delivery.DefaultZones()
+	if path := os.Getenv("DELIVERY_ZONES"); path != "" {
+		var err error
+		zones, err = delivery.LoadZones(path)
+		if err != nil {
+			slog.Error("failed to load delivery zones", "path", path, "error", err)
+			os.Exit(1)
+		}
+		slog.Info("delivery zones loaded", "path", path, "count", len(zones))
+	}
+
+	// Create delivery instance; batching is disabled unless a batch window is set
+	d := delivery.NewDeliveryWithConfig(delivery.DeliveryConfig{
+		Model:        &model,
+		Zones:        zones,
+		BatchWindow:  time.Duration(envInt("DELIVERY_BATCH_WINDOW", 0)) * time.Second,
+		MaxBatchSize: envInt("DELIVERY_MAX_BATCH_SIZE", delivery.DefaultMaxBatchSize),
+		MaxWait:      time.Duration(envInt("DELIVERY_MAX_WAIT", 0)) * time.Second,
+		RepairTime:   time.Duration(envInt("DELIVERY_REPAIR_TIME", int(delivery.DefaultRepairTime.Seconds()))) * time.Second,
+		FailureRates: map[string]float64{
+			delivery.FailureCustomerNotHome:  envFloat("DELIVERY_CUSTOMER_NOT_HOME_RATE", 0),
+			delivery.FailureAddressNotFound:  envFloat("DELIVERY_ADDRESS_NOT_FOUND_RATE", 0),
+			delivery.FailureVehicleBreakdown: envFloat("DELIVERY_BREAKDOWN_RATE", 0),
+		},
+	})
 
 	// Set up router with middleware
 	r := chi.NewRouter()
@@ -34,6 +64,9 @@ func main() {
 
 	// Register routes
 	r.Post("/deliver", d.HandleDeliver)
+	r.Get("/deliver/{orderId}/proof", d.HandleGetProof)
+	r.Post("/delivery/quote", d.HandleQuote)
+	r.Get("/drivers", d.HandleGetDrivers)
 
 	// Health check endpoint
 	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
@@ -71,3 +104,33 @@ func main() {
 	}
 	slog.Info("delivery service stopped")
 }
+
+// envInt reads an integer from the environment, falling back to def when the
+// variable is unset or invalid.
+func envInt(key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		slog.Warn("invalid integer in environment, using default", "key", key, "value", v, "default", def)
+		return def
+	}
+	return n
+}
+
+// envFloat reads a floating point number from the environment, falling back to
+// def when the variable is unset or invalid.
+func envFloat(key string, def float64) float64 {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		slog.Warn("invalid number in environment, using default", "key", key, "value", v, "default", def)
+		return def
+	}
+	return f
+}
@@ -0,0 +1,281 @@
+package delivery
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// deliveryJob is a delivery request waiting for a driver.
+type deliveryJob struct {
+	req      DeliverRequest
+	queuedAt time.Time
+}
+
+// tripStop is an order on a driver's trip, where it is headed, who receives
+// it and the number of seconds after leaving the store at which the driver
+// reaches it. The destination is nil for orders without an address.
+type tripStop struct {
+	orderID     uuid.UUID
+	destination *Coordinates
+	recipient   string
+	arrival     int
+}
+
+// assignment is the driver an order was handed to and the number of seconds
+// after which the driver reaches the order's stop.
+type assignment struct {
+	driverID string
+	arrival  int
+}
+
+// dispatch queues a delivery request and assigns queued deliveries to idle
+// drivers. It returns the assignment of this request, whose driverID is empty
+// if the request is waiting for a driver.
+func (d *Delivery) dispatch(req DeliverRequest) assignment {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.pending = append(d.pending, &deliveryJob{req: req, queuedAt: time.Now()})
+	return d.startPending()[req.OrderID]
+}
+
+// startPending assigns queued deliveries to idle drivers, highest ranked
+// first, and returns the assignments made by order ID. With batching enabled
+// each trip takes the head of the queue together with other orders headed
+// nearby; the head is held until its batch window closes or the batch is full.
+// The caller must hold d.mu.
+func (d *Delivery) startPending() map[uuid.UUID]assignment {
+	now := time.Now()
+	sort.SliceStable(d.pending, func(i, j int) bool {
+		return d.rank(d.pending[i], now) > d.rank(d.pending[j], now)
+	})
+
+	assigned := make(map[uuid.UUID]assignment)
+	for len(d.pending) > 0 {
+		driver := d.idleDriver()
+		if driver == nil {
+			break
+		}
+
+		batch, stops := d.nextBatch(driver.VehicleType, now)
+		if open := d.pending[0].queuedAt.Add(d.batchWindow).Sub(now); open > 0 && len(batch) < d.maxBatchSize {
+			d.scheduleBatch(open)
+			break
+		}
+		d.removePending(batch)
+
+		orderIDs := make([]uuid.UUID, 0, len(batch))
+		for i, job := range batch {
+			orderID := job.req.OrderID
+			orderIDs = append(orderIDs, orderID)
+			assigned[orderID] = assignment{driverID: driver.ID, arrival: stops[i].arrival}
+			slog.Info("delivery assigned", "orderId", orderID, "driverId", driver.ID, "priority", job.req.Priority, "waited", now.Sub(job.queuedAt).Round(time.Millisecond))
+		}
+		driver.Status = DriverEnRoute
+		driver.OrderID = &orderIDs[0]
+		driver.OrderIDs = orderIDs
+		driver.UpdatedAt = now
+
+		go d.runTrip(stops, driver.ID)
+	}
+	return assigned
+}
+
+// nextBatch picks the orders for the next trip: the head of the queue plus,
+// in rank order, other queued orders headed near it, up to the maximum batch
+// size. An order only joins the batch if it would still be delivered within
+// the maximum wait. It returns the orders with their stops in delivery order,
+// timed for the given vehicle. The caller must hold d.mu and have sorted
+// d.pending.
+func (d *Delivery) nextBatch(vehicle string, now time.Time) ([]*deliveryJob, []tripStop) {
+	head := d.pending[0]
+	batch := []*deliveryJob{head}
+	arrival := d.legTime(vehicle, nil, head)
+	stops := []tripStop{newTripStop(head, arrival)}
+	if d.batchWindow <= 0 {
+		return batch, stops
+	}
+
+	for _, job := range d.pending[1:] {
+		if len(batch) >= d.maxBatchSize {
+			break
+		}
+		if !d.nearby(head, job) {
+			continue
+		}
+		last := batch[len(batch)-1]
+		leg := d.legTime(vehicle, last, job)
+		if d.maxWait > 0 && now.Sub(job.queuedAt)+time.Duration(arrival+leg)*time.Second > d.maxWait {
+			continue
+		}
+		arrival += leg
+		batch = append(batch, job)
+		stops = append(stops, newTripStop(job, arrival))
+	}
+	return batch, stops
+}
+
+// newTripStop returns the stop of the job on a trip.
+func newTripStop(job *deliveryJob, arrival int) tripStop {
+	stop := tripStop{orderID: job.req.OrderID, arrival: arrival}
+	if job.req.DeliveryAddress != nil {
+		destination := job.req.DeliveryAddress.coordinates()
+		stop.destination = &destination
+		stop.recipient = job.req.DeliveryAddress.Recipient
+	}
+	return stop
+}
+
+// nearby reports whether the job is headed within the batch radius of the
+// head of a batch. Orders without an address cannot be located and are
+// always considered nearby.
+func (d *Delivery) nearby(head, job *deliveryJob) bool {
+	if head.req.DeliveryAddress == nil || job.req.DeliveryAddress == nil {
+		return true
+	}
+	return distanceKm(head.req.DeliveryAddress.coordinates(), job.req.DeliveryAddress.coordinates()) <= d.model.BatchRadiusKm
+}
+
+// legTime returns the seconds the vehicle needs from the previous stop of a
+// trip, or from the store when from is nil, to the job's destination. Orders
+// without an address take the time returned by the delivery time function.
+func (d *Delivery) legTime(vehicle string, from, to *deliveryJob) int {
+	if to.req.DeliveryAddress == nil {
+		return d.deliveryTimeFunc()
+	}
+	start := d.model.Store
+	if from != nil && from.req.DeliveryAddress != nil {
+		start = from.req.DeliveryAddress.coordinates()
+	}
+	return d.model.travelTime(vehicle, start, to.req.DeliveryAddress.coordinates())
+}
+
+// removePending drops the given jobs from the queue. The caller must hold d.mu.
+func (d *Delivery) removePending(jobs []*deliveryJob) {
+	taken := make(map[*deliveryJob]bool, len(jobs))
+	for _, job := range jobs {
+		taken[job] = true
+	}
+	pending := d.pending[:0]
+	for _, job := range d.pending {
+		if !taken[job] {
+			pending = append(pending, job)
+		}
+	}
+	d.pending = pending
+}
+
+// scheduleBatch makes the dispatcher look at the queue again once the batch
+// window of the head of the queue has closed. The caller must hold d.mu.
+func (d *Delivery) scheduleBatch(after time.Duration) {
+	if d.batchTimer != nil {
+		d.batchTimer.Stop()
+	}
+	d.batchTimer = time.AfterFunc(after, func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		d.startPending()
+	})
+}
+
+// idleDriver returns the driver who has been idle the longest, or nil if no
+// driver is idle. The caller must hold d.mu.
+func (d *Delivery) idleDriver() *Driver {
+	var best *Driver
+	for _, driver := range d.drivers {
+		if driver.Status != DriverIdle {
+			continue
+		}
+		if best == nil || driver.UpdatedAt.Before(best.UpdatedAt) ||
+			(driver.UpdatedAt.Equal(best.UpdatedAt) && driver.ID < best.ID) {
+			best = driver
+		}
+	}
+	return best
+}
+
+// runTrip has the driver deliver the orders of a trip and drive back to the
+// store, after which the driver is idle again and picks up the next queued
+// deliveries (background; detached from the request context). A driver whose
+// vehicle breaks down goes off shift instead until the vehicle is repaired.
+func (d *Delivery) runTrip(stops []tripStop, driverID string) {
+	tripTime, brokeDown := d.deliverTrip(context.Background(), stops, driverID)
+	if brokeDown {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		if driver, ok := d.drivers[driverID]; ok {
+			driver.Status = DriverOffShift
+			driver.OrderID = nil
+			driver.OrderIDs = nil
+			driver.UpdatedAt = time.Now()
+		}
+		slog.Warn("driver off shift after breakdown", "driverId", driverID, "repairTime", d.repairTime)
+		time.AfterFunc(d.repairTime, func() { d.backOnShift(driverID) })
+		return
+	}
+
+	returnTime := d.returnTimeFunc(tripTime)
+	d.setDriverStatus(driverID, DriverReturning)
+	slog.Info("driver returning", "driverId", driverID, "returnTime", returnTime)
+	time.Sleep(time.Duration(returnTime) * time.Second)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if driver, ok := d.drivers[driverID]; ok {
+		driver.Status = DriverIdle
+		driver.OrderID = nil
+		driver.OrderIDs = nil
+		driver.UpdatedAt = time.Now()
+	}
+	slog.Info("driver idle", "driverId", driverID)
+	d.startPending()
+}
+
+// backOnShift makes a driver whose vehicle has been repaired idle again, so
+// they pick up the next queued deliveries.
+func (d *Delivery) backOnShift(driverID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	driver, ok := d.drivers[driverID]
+	if !ok || driver.Status != DriverOffShift {
+		return
+	}
+	driver.Status = DriverIdle
+	driver.UpdatedAt = time.Now()
+	slog.Info("driver back on shift", "driverId", driverID)
+	d.startPending()
+}
+
+// setDriverStatus updates the status of a driver.
+func (d *Delivery) setDriverStatus(driverID, status string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if driver, ok := d.drivers[driverID]; ok {
+		driver.Status = status
+		driver.UpdatedAt = time.Now()
+	}
+}
+
+// Drivers returns a snapshot of the driver fleet ordered by driver ID.
+func (d *Delivery) Drivers() []Driver {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	drivers := make([]Driver, 0, len(d.drivers))
+	for _, driver := range d.drivers {
+		drivers = append(drivers, *driver)
+	}
+	sort.Slice(drivers, func(i, j int) bool { return drivers[i].ID < drivers[j].ID })
+	return drivers
+}
+
+// rank returns the job's effective priority: its priority level plus one
+// level for every aging interval it has spent in the queue.
+func (d *Delivery) rank(job *deliveryJob, now time.Time) float64 {
+	waited := now.Sub(job.queuedAt)
+	return float64(priorityLevels[job.req.Priority]) + float64(waited)/float64(d.agingInterval)
+}
@@ -0,0 +1,111 @@
+package delivery
+
+import "math"
+
+// earthRadiusKm is the mean radius of the Earth used for great-circle distances.
+const earthRadiusKm = 6371.0
+
+// Coordinates is a point on the map in decimal degrees.
+type Coordinates struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+// Location is the position of a driver on the way to an order, sent with
+// delivery progress events. Heading is the direction of travel in degrees
+// clockwise from north, and ETASeconds the time left until the order's stop.
+type Location struct {
+	Lat        float64 `json:"lat"`
+	Lon        float64 `json:"lon"`
+	Heading    float64 `json:"heading"`
+	ETASeconds int     `json:"etaSeconds"`
+}
+
+// DeliveryModel is the offline model used to estimate driving times from the
+// distance between two points. Road distance is the great-circle distance
+// multiplied by RoadFactor, driven at the vehicle's speed. TimeScale converts
+// real-world seconds into simulated seconds so deliveries stay short.
+type DeliveryModel struct {
+	Store          Coordinates        // Location of the store, where every trip starts
+	SpeedKmh       map[string]float64 // Average speed by vehicle type
+	RoadFactor     float64            // Road distance over straight-line distance
+	HandoffSeconds int                // Simulated seconds spent handing the order over at the door
+	TimeScale      float64            // Simulated seconds per real-world second
+	BatchRadiusKm  float64            // Maximum distance between the stops of one batched trip
+}
+
+// DefaultDeliveryModel returns the delivery model for the store in the city
+// center, where one real-world minute of driving takes one simulated second.
+func DefaultDeliveryModel() DeliveryModel {
+	return DeliveryModel{
+		Store: Coordinates{Lat: 41.3874, Lon: 2.1686},
+		SpeedKmh: map[string]float64{
+			VehicleBike:    15,
+			VehicleScooter: 25,
+			VehicleCar:     30,
+		},
+		RoadFactor:     1.3,
+		HandoffSeconds: 1,
+		TimeScale:      1.0 / 60,
+		BatchRadiusKm:  2,
+	}
+}
+
+// distanceKm returns the great-circle distance between two points using the
+// haversine formula.
+func distanceKm(a, b Coordinates) float64 {
+	lat1, lat2 := a.Lat*math.Pi/180, b.Lat*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (b.Lon - a.Lon) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// travelTime returns the simulated seconds a vehicle needs to drive from one
+// point to another and hand the order over. It is always at least one second.
+func (m DeliveryModel) travelTime(vehicle string, from, to Coordinates) int {
+	speed := m.SpeedKmh[vehicle]
+	if speed <= 0 {
+		speed = m.SpeedKmh[VehicleScooter]
+	}
+	hours := distanceKm(from, to) * m.RoadFactor / speed
+	return max(1, int(math.Round(hours*3600*m.TimeScale))+m.HandoffSeconds)
+}
+
+// bearing returns the initial heading from one point to another in degrees
+// clockwise from north, in the range [0, 360).
+func bearing(from, to Coordinates) float64 {
+	lat1, lat2 := from.Lat*math.Pi/180, to.Lat*math.Pi/180
+	dLon := (to.Lon - from.Lon) * math.Pi / 180
+
+	y := math.Sin(dLon) * math.Cos(lat2)
+	x := math.Cos(lat1)*math.Sin(lat2) - math.Sin(lat1)*math.Cos(lat2)*math.Cos(dLon)
+	return math.Mod(math.Atan2(y, x)*180/math.Pi+360, 360)
+}
+
+// positionAt returns where a driver who left origin is after elapsed seconds
+// of a trip, driving in a straight line from stop to stop, along with the
+// heading of the current leg. It returns false if a stop of the trip has no
+// destination, as the route is then unknown.
+func positionAt(origin Coordinates, stops []tripStop, elapsed int) (Coordinates, float64, bool) {
+	from, fromTime, heading := origin, 0, 0.0
+	for _, stop := range stops {
+		if stop.destination == nil {
+			return Coordinates{}, 0, false
+		}
+		to := *stop.destination
+		if from != to {
+			heading = bearing(from, to)
+		}
+		if elapsed < stop.arrival {
+			frac := float64(elapsed-fromTime) / float64(stop.arrival-fromTime)
+			return Coordinates{
+				Lat: from.Lat + (to.Lat-from.Lat)*frac,
+				Lon: from.Lon + (to.Lon-from.Lon)*frac,
+			}, heading, true
+		}
+		from, fromTime = to, stop.arrival
+	}
+	return from, heading, true
+}
@@ -0,0 +1,108 @@
+package delivery
+
+import (
+	"math"
+	"testing"
+)
+
+// TestDistanceKm tests the great-circle distance between two cities.
+func TestDistanceKm(t *testing.T) {
+	barcelona := Coordinates{Lat: 41.3874, Lon: 2.1686}
+	madrid := Coordinates{Lat: 40.4168, Lon: -3.7038}
+
+	if got := distanceKm(barcelona, madrid); math.Abs(got-505) > 5 {
+		t.Errorf("expected about 505 km between Barcelona and Madrid, got %.1f", got)
+	}
+	if got := distanceKm(barcelona, barcelona); got != 0 {
+		t.Errorf("expected 0 km to the same point, got %.1f", got)
+	}
+}
+
+// TestTravelTimeDependsOnDistanceAndVehicle tests that farther destinations and slower vehicles take longer.
+func TestTravelTimeDependsOnDistanceAndVehicle(t *testing.T) {
+	model := DefaultDeliveryModel()
+	near := Coordinates{Lat: model.Store.Lat + 0.01, Lon: model.Store.Lon}
+	far := Coordinates{Lat: model.Store.Lat + 0.05, Lon: model.Store.Lon}
+
+	if model.travelTime(VehicleScooter, model.Store, far) <= model.travelTime(VehicleScooter, model.Store, near) {
+		t.Error("expected a farther destination to take longer")
+	}
+	if model.travelTime(VehicleBike, model.Store, far) <= model.travelTime(VehicleCar, model.Store, far) {
+		t.Error("expected a bike to take longer than a car")
+	}
+	if got := model.travelTime(VehicleScooter, model.Store, model.Store); got != model.HandoffSeconds {
+		t.Errorf("expected only the handoff time for the store itself, got %d", got)
+	}
+}
+
+// TestTravelTimeUsesTimeScale tests the conversion of real driving time to simulated seconds.
+func TestTravelTimeUsesTimeScale(t *testing.T) {
+	model := DeliveryModel{
+		SpeedKmh:   map[string]float64{VehicleCar: 60},
+		RoadFactor: 1,
+		TimeScale:  1.0 / 60,
+	}
+	// About 11.1 km at 60 km/h is 11.1 minutes, or 11 simulated seconds
+	to := Coordinates{Lat: 0.1, Lon: 0}
+	if got := model.travelTime(VehicleCar, Coordinates{}, to); got != 11 {
+		t.Errorf("expected 11 seconds, got %d", got)
+	}
+}
+
+// TestBearing tests the heading between points in each cardinal direction.
+func TestBearing(t *testing.T) {
+	origin := Coordinates{Lat: 41.38, Lon: 2.16}
+	tests := []struct {
+		to   Coordinates
+		want float64
+	}{
+		{Coordinates{Lat: 41.40, Lon: 2.16}, 0},
+		{Coordinates{Lat: 41.38, Lon: 2.18}, 90},
+		{Coordinates{Lat: 41.36, Lon: 2.16}, 180},
+		{Coordinates{Lat: 41.38, Lon: 2.14}, 270},
+	}
+	for _, tt := range tests {
+		if got := bearing(origin, tt.to); math.Abs(got-tt.want) > 0.5 {
+			t.Errorf("expected heading %.0f to %+v, got %.1f", tt.want, tt.to, got)
+		}
+	}
+}
+
+// TestPositionAtFollowsStops tests that the driver moves in a straight line from the store to each stop in turn.
+func TestPositionAtFollowsStops(t *testing.T) {
+	origin := Coordinates{Lat: 0, Lon: 0}
+	first := Coordinates{Lat: 0.04, Lon: 0}
+	second := Coordinates{Lat: 0.04, Lon: 0.02}
+	stops := []tripStop{
+		{destination: &first, arrival: 4},
+		{destination: &second, arrival: 6},
+	}
+
+	tests := []struct {
+		elapsed int
+		want    Coordinates
+		heading float64
+	}{
+		{0, origin, 0},
+		{1, Coordinates{Lat: 0.01, Lon: 0}, 0},
+		{4, first, 90},
+		{5, Coordinates{Lat: 0.04, Lon: 0.01}, 90},
+		{6, second, 90},
+	}
+	for _, tt := range tests {
+		got, heading, ok := positionAt(origin, stops, tt.elapsed)
+		if !ok {
+			t.Fatalf("expected a known route at %ds", tt.elapsed)
+		}
+		if math.Abs(got.Lat-tt.want.Lat) > 1e-9 || math.Abs(got.Lon-tt.want.Lon) > 1e-9 {
+			t.Errorf("at %ds: expected %+v, got %+v", tt.elapsed, tt.want, got)
+		}
+		if math.Abs(heading-tt.heading) > 0.5 {
+			t.Errorf("at %ds: expected heading %.0f, got %.1f", tt.elapsed, tt.heading, heading)
+		}
+	}
+
+	if _, _, ok := positionAt(origin, []tripStop{{arrival: 3}}, 1); ok {
+		t.Error("expected an unknown route for a stop without destination")
+	}
+}
@@ -8,46 +8,104 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"math"
 	"math/rand"
 	"net/http"
+	"sync"
 	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 )
 
+// DefaultAgingInterval is how long an order waits for a driver before it is
+// ranked one priority level higher, so normal orders are not starved.
+const DefaultAgingInterval = 30 * time.Second
+
+// DefaultMaxBatchSize is the default maximum number of orders a driver
+// delivers in one trip when batching is enabled.
+const DefaultMaxBatchSize = 3
+
+// DefaultRepairTime is how long a driver whose vehicle broke down stays off
+// shift before they are back and can take deliveries again.
+const DefaultRepairTime = 5 * time.Minute
+
 // DeliveryConfig contains configuration options for the Delivery service.
 type DeliveryConfig struct {
 	StoreURL         string
-	DeliveryTimeFunc func() int // Returns delivery time in seconds
+	DeliveryTimeFunc func() int                 // Returns delivery time in seconds
+	ReturnTimeFunc   func(deliveryTime int) int // Returns the driver's return trip time in seconds
+	Drivers          map[string]*Driver         // Driver fleet; defaults to DefaultDrivers
+	AgingInterval    time.Duration              // Waiting time after which a queued order gains one priority level
+	BatchWindow      time.Duration              // How long an order is held for other orders to share its trip; 0 disables batching
+	MaxBatchSize     int                        // Maximum number of orders in one trip
+	MaxWait          time.Duration              // Maximum time from request to delivery for orders joining a batch; 0 means no limit
+	Model            *DeliveryModel             // Model estimating delivery times from addresses; defaults to DefaultDeliveryModel
+	FailureRates     map[string]float64         // Probability between 0 and 1 of each failure mode; defaults to no failures
+	Zones            []Zone                     // Delivery zones, matched in order; defaults to DefaultZones
+	RepairTime       time.Duration              // How long a driver is off shift after a breakdown
 }
 
 // OrderEvent represents an event sent to the store service.
 type OrderEvent struct {
-	OrderID uuid.UUID `json:"orderId"`
-	Status  string    `json:"status"`
-	Source  string    `json:"source"`
+	OrderID  uuid.UUID  `json:"orderId"`
+	Status   string     `json:"status"`
+	Source   string     `json:"source"`
+	DriverID string     `json:"driverId,omitempty"`
+	Stop     int        `json:"stop,omitempty"` // 1-based stop of the order on the driver's trip
+	Location *Location  `json:"location,omitempty"`
+	Reason   string     `json:"reason,omitempty"`  // failure mode of a DELIVERY_FAILED event
+	ProofID  *uuid.UUID `json:"proofId,omitempty"` // proof of delivery of a DELIVERED event
 }
 
 // Delivery manages pizza delivery operations and provides HTTP handlers for the delivery service.
+// Orders are assigned to idle drivers of the fleet; when no driver is idle they wait in a
+// dispatch queue ordered by priority.
 type Delivery struct {
+	rngMu            sync.Mutex
 	rng              *rand.Rand
 	storeURL         string
 	httpClient       *http.Client
 	deliveryTimeFunc func() int
+	returnTimeFunc   func(deliveryTime int) int
+	agingInterval    time.Duration
+	batchWindow      time.Duration
+	maxBatchSize     int
+	maxWait          time.Duration
+	model            DeliveryModel
+	failureRates     map[string]float64
+	zones            []Zone
+	repairTime       time.Duration
+
+	mu         sync.Mutex
+	pending    []*deliveryJob
+	drivers    map[string]*Driver
+	proofs     map[uuid.UUID]*ProofOfDelivery
+	batchTimer *time.Timer
 }
 
-// NewDelivery creates a new Delivery instance with a seeded random number generator.
-// The default delivery time is a random interval between 5 and 20 seconds.
+// NewDelivery creates a new Delivery instance with a seeded random number generator
+// and the default driver fleet. Delivery times are estimated from the distance
+// to the delivery address; orders without an address take a random 5 to 20
+// seconds. Drivers take as long to return as to deliver.
 func NewDelivery() *Delivery {
-	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
-	return &Delivery{
-		rng:      rng,
+	d := &Delivery{
+		rng:      rand.New(rand.NewSource(time.Now().UnixNano())),
 		storeURL: "http://store:8080",
 		httpClient: &http.Client{
 			Timeout: 10 * time.Second,
 		},
-		deliveryTimeFunc: func() int { return rng.Intn(16) + 5 },
+		returnTimeFunc: func(deliveryTime int) int { return deliveryTime },
+		agingInterval:  DefaultAgingInterval,
+		maxBatchSize:   DefaultMaxBatchSize,
+		model:          DefaultDeliveryModel(),
+		zones:          DefaultZones(),
+		repairTime:     DefaultRepairTime,
+		drivers:        DefaultDrivers(),
+		proofs:         make(map[uuid.UUID]*ProofOfDelivery),
 	}
+	d.deliveryTimeFunc = func() int { return d.randIntn(16) + 5 }
+	return d
 }
 
 // NewDeliveryWithConfig creates a new Delivery instance with the given configuration.
@@ -59,11 +117,58 @@ func NewDeliveryWithConfig(config DeliveryConfig) *Delivery {
 	if config.DeliveryTimeFunc != nil {
 		d.deliveryTimeFunc = config.DeliveryTimeFunc
 	}
+	if config.ReturnTimeFunc != nil {
+		d.returnTimeFunc = config.ReturnTimeFunc
+	}
+	if config.Drivers != nil {
+		d.drivers = config.Drivers
+	}
+	if config.AgingInterval > 0 {
+		d.agingInterval = config.AgingInterval
+	}
+	if config.BatchWindow > 0 {
+		d.batchWindow = config.BatchWindow
+	}
+	if config.MaxBatchSize > 0 {
+		d.maxBatchSize = config.MaxBatchSize
+	}
+	if config.MaxWait > 0 {
+		d.maxWait = config.MaxWait
+	}
+	if config.Model != nil {
+		d.model = *config.Model
+	}
+	if config.FailureRates != nil {
+		d.failureRates = config.FailureRates
+	}
+	if config.Zones != nil {
+		d.zones = config.Zones
+	}
+	if config.RepairTime > 0 {
+		d.repairTime = config.RepairTime
+	}
 	return d
 }
 
+// randIntn returns a random number in [0, n) from the delivery's generator.
+// It is safe to call from concurrent deliveries.
+func (d *Delivery) randIntn(n int) int {
+	d.rngMu.Lock()
+	defer d.rngMu.Unlock()
+	return d.rng.Intn(n)
+}
+
+// randFloat64 returns a random number in [0.0, 1.0) from the delivery's generator.
+func (d *Delivery) randFloat64() float64 {
+	d.rngMu.Lock()
+	defer d.rngMu.Unlock()
+	return d.rng.Float64()
+}
+
 // HandleDeliver handles POST /deliver requests to deliver pizza orders.
-// It validates the request and starts the delivery simulation asynchronously.
+// It validates the request and assigns the order to an idle driver, or queues
+// it until one becomes available or, with batching enabled, until its batch
+// window closes. The delivery is simulated asynchronously.
 func (d *Delivery) HandleDeliver(w http.ResponseWriter, r *http.Request) {
 	var req DeliverRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -77,16 +182,40 @@ func (d *Delivery) HandleDeliver(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	slog.Info("delivery request received", "orderId", req.OrderID, "items", len(req.OrderItems))
+	if req.Priority == "" {
+		req.Priority = PriorityNormal
+	}
+	if _, ok := priorityLevels[req.Priority]; !ok {
+		http.Error(w, "Invalid priority", http.StatusBadRequest)
+		return
+	}
+
+	if req.DeliveryAddress != nil && !req.DeliveryAddress.Valid() {
+		http.Error(w, "Invalid delivery address", http.StatusBadRequest)
+		return
+	}
+
+	slog.Info("delivery request received", "orderId", req.OrderID, "items", len(req.OrderItems), "priority", req.Priority)
 
-	// Start delivery in a goroutine (background; detach from request context)
-	go d.deliverOrder(context.Background(), req.OrderID)
+	// Hand the order to the dispatcher, which starts it as soon as a driver is idle
+	assigned := d.dispatch(req)
 
 	// Return accepted response immediately
 	resp := DeliverResponse{
-		OrderID: req.OrderID,
-		Status:  "delivering",
-		Message: fmt.Sprintf("Started delivering %d item(s)", len(req.OrderItems)),
+		OrderID:          req.OrderID,
+		Status:           "delivering",
+		DriverID:         assigned.driverID,
+		EstimatedSeconds: assigned.arrival,
+		Message:          fmt.Sprintf("Started delivering %d item(s)", len(req.OrderItems)),
+	}
+	if assigned.driverID == "" {
+		resp.Status = "queued"
+		resp.EstimatedSeconds = d.estimate(req)
+		resp.Message = fmt.Sprintf("Waiting for a driver to deliver %d item(s)", len(req.OrderItems))
+	}
+	if resp.EstimatedSeconds > 0 {
+		eta := time.Now().Add(time.Duration(resp.EstimatedSeconds) * time.Second)
+		resp.ETA = &eta
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -94,42 +223,195 @@ func (d *Delivery) HandleDeliver(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(resp)
 }
 
-// deliverOrder simulates delivering an order with a random delivery time between 5-20 seconds.
-// It sends percentage-based progress updates every second and a final DELIVERED event.
-func (d *Delivery) deliverOrder(ctx context.Context, orderID uuid.UUID) {
-	deliveryTime := d.deliveryTimeFunc()
+// HandleQuote handles POST /delivery/quote requests.
+// It returns the delivery zone, fee and delivery times for an address, or
+// 422 Unprocessable Entity if the address is outside every delivery zone.
+func (d *Delivery) HandleQuote(w http.ResponseWriter, r *http.Request) {
+	var req QuoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if !req.DeliveryAddress.Valid() {
+		http.Error(w, "Invalid delivery address", http.StatusBadRequest)
+		return
+	}
+
+	point := req.DeliveryAddress.coordinates()
+	zone, err := d.zoneFor(point)
+	if err != nil {
+		slog.Info("delivery address not serviceable", "lat", point.Lat, "lon", point.Lon)
+		http.Error(w, "Address is outside the delivery zones", http.StatusUnprocessableEntity)
+		return
+	}
+
+	resp := QuoteResponse{
+		Zone:             zone.Name,
+		Fee:              zone.Fee,
+		MaxDeliveryTime:  zone.MaxDeliveryTime,
+		EstimatedSeconds: d.model.travelTime(VehicleScooter, d.model.Store, point),
+	}
+	slog.Info("delivery quoted", "zone", resp.Zone, "fee", resp.Fee, "estimatedSeconds", resp.EstimatedSeconds)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		slog.Error("failed to encode quote", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleGetDrivers handles GET /drivers requests.
+// Returns the driver fleet with each driver's status, ordered by driver ID.
+func (d *Delivery) HandleGetDrivers(w http.ResponseWriter, r *http.Request) {
+	drivers := d.Drivers()
+	slog.Info("getting drivers", "count", len(drivers))
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(drivers); err != nil {
+		slog.Error("failed to encode drivers", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleGetProof handles GET /deliver/{orderId}/proof requests.
+// Returns the proof of delivery of the order, or 404 if the order has not
+// been delivered.
+func (d *Delivery) HandleGetProof(w http.ResponseWriter, r *http.Request) {
+	orderID, err := uuid.Parse(chi.URLParam(r, "orderId"))
+	if err != nil {
+		http.Error(w, "Invalid orderId format", http.StatusBadRequest)
+		return
+	}
+
+	proof, ok := d.Proof(orderID)
+	if !ok {
+		slog.Warn("proof of delivery not found", "orderId", orderID)
+		http.Error(w, "Proof of delivery not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(proof); err != nil {
+		slog.Error("failed to encode proof of delivery", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// estimate returns the seconds a queued order takes to be delivered once it
+// leaves the store on a scooter, including the batch window it is held for.
+// It returns 0 for orders without an address, whose delivery time is random.
+func (d *Delivery) estimate(req DeliverRequest) int {
+	if req.DeliveryAddress == nil {
+		return 0
+	}
+	return int(d.batchWindow.Seconds()) + d.model.travelTime(VehicleScooter, d.model.Store, req.DeliveryAddress.coordinates())
+}
+
+// deliverTrip simulates a driver delivering one or more orders, stop after stop.
+// Every second it sends each undelivered order a percentage-based progress update
+// towards its own stop, and a DELIVERED event when the driver reaches that stop.
+// When every stop has an address, events carry the driver's simulated location
+// on a straight-line route.
+//
+// Orders that cannot be handed over at their stop, and all orders still on board
+// when the vehicle breaks down, get a DELIVERY_FAILED event with the reason. It
+// returns the seconds driven and whether the vehicle broke down.
+func (d *Delivery) deliverTrip(ctx context.Context, stops []tripStop, driverID string) (int, bool) {
+	tripTime := stops[len(stops)-1].arrival
 	startTime := time.Now()
-	slog.Info("delivery started", "orderId", orderID, "deliveryTime", deliveryTime)
+	slog.Info("delivery started", "driverId", driverID, "stops", len(stops), "tripTime", tripTime)
+
+	breakdownAt := 0
+	if tripTime > 0 && d.randFloat64() < d.failureRates[FailureVehicleBreakdown] {
+		breakdownAt = d.randIntn(tripTime) + 1
+	}
+
+	// deliverReached hands over the orders of the stops reached after elapsed seconds
+	next := 0
+	deliverReached := func(elapsed int) {
+		for next < len(stops) && stops[next].arrival <= elapsed {
+			stop := stops[next]
+			event := OrderEvent{OrderID: stop.orderID, Status: "DELIVERED", DriverID: driverID, Stop: next + 1, Location: d.location(stops, stop, elapsed)}
+			if reason := d.handoverFailure(); reason != "" {
+				slog.Warn("delivery failed", "orderId", stop.orderID, "driverId", driverID, "stop", next+1, "reason", reason)
+				event.Status = "DELIVERY_FAILED"
+				event.Reason = reason
+			} else {
+				slog.Info("delivery completed", "orderId", stop.orderID, "driverId", driverID, "stop", next+1, "duration", time.Since(startTime).Round(time.Second))
+				event.ProofID = &d.recordProof(stop, driverID).ID
+			}
+			d.sendEvent(ctx, event)
+			next++
+		}
+	}
 
-	for elapsed := 1; elapsed <= deliveryTime; elapsed++ {
+	deliverReached(0)
+	for elapsed := 1; elapsed <= tripTime; elapsed++ {
 		select {
 		case <-ctx.Done():
-			slog.Warn("delivery cancelled", "orderId", orderID, "error", ctx.Err())
-			return
+			slog.Warn("delivery cancelled", "driverId", driverID, "error", ctx.Err())
+			return elapsed - 1, false
 		default:
 		}
 
 		time.Sleep(1 * time.Second)
 
-		// Calculate and send percentage update
-		percent := (elapsed * 100) / deliveryTime
-		d.sendEvent(ctx, orderID, fmt.Sprintf("delivering %d%%", percent))
+		if elapsed == breakdownAt {
+			slog.Warn("vehicle broke down", "driverId", driverID, "elapsed", elapsed, "undelivered", len(stops)-next)
+			for i := next; i < len(stops); i++ {
+				d.sendEvent(ctx, OrderEvent{OrderID: stops[i].orderID, Status: "DELIVERY_FAILED", Reason: FailureVehicleBreakdown, DriverID: driverID, Stop: i + 1, Location: d.location(stops, stops[i], elapsed)})
+			}
+			return elapsed, true
+		}
+
+		// Calculate and send percentage updates for the orders still on board
+		for i := next; i < len(stops); i++ {
+			percent := (elapsed * 100) / stops[i].arrival
+			d.sendEvent(ctx, OrderEvent{OrderID: stops[i].orderID, Status: fmt.Sprintf("delivering %d%%", percent), DriverID: driverID, Stop: i + 1, Location: d.location(stops, stops[i], elapsed)})
+		}
+		deliverReached(elapsed)
 	}
+	return tripTime, false
+}
 
-	duration := time.Since(startTime)
-	slog.Info("delivery completed", "orderId", orderID, "duration", duration.Round(time.Second))
+// handoverFailure rolls the failure modes that can happen at the door and
+// returns the reason the order could not be handed over, or an empty string
+// if it was delivered.
+func (d *Delivery) handoverFailure() string {
+	roll := d.randFloat64()
+	for _, reason := range []string{FailureCustomerNotHome, FailureAddressNotFound} {
+		rate := d.failureRates[reason]
+		if roll < rate {
+			return reason
+		}
+		roll -= rate
+	}
+	return ""
+}
 
-	// Send DELIVERED event
-	d.sendEvent(ctx, orderID, "DELIVERED")
+// location returns the driver's location after elapsed seconds of the trip,
+// with the time left until the given stop, or nil if the route is unknown.
+func (d *Delivery) location(stops []tripStop, stop tripStop, elapsed int) *Location {
+	position, heading, ok := positionAt(d.model.Store, stops, elapsed)
+	if !ok {
+		return nil
+	}
+	return &Location{
+		Lat:        position.Lat,
+		Lon:        position.Lon,
+		Heading:    math.Round(heading),
+		ETASeconds: max(0, stop.arrival-elapsed),
+	}
 }
 
 // sendEvent sends an event to the store service.
-func (d *Delivery) sendEvent(ctx context.Context, orderID uuid.UUID, status string) {
-	event := OrderEvent{
-		OrderID: orderID,
-		Status:  status,
-		Source:  "delivery",
-	}
+func (d *Delivery) sendEvent(ctx context.Context, event OrderEvent) {
+	event.Source = "delivery"
+	orderID := event.OrderID
 
 	body, err := json.Marshal(event)
 	if err != nil {
@@ -146,7 +428,7 @@ func (d *Delivery) sendEvent(ctx context.Context, orderID uuid.UUID, status stri
 
 	resp, err := d.httpClient.Do(req)
 	if err != nil {
-		slog.Error("failed to send event to store", "orderId", orderID, "status", status, "error", err)
+		slog.Error("failed to send event to store", "orderId", orderID, "status", event.Status, "error", err)
 		return
 	}
 	defer resp.Body.Close()
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
 	"time"
 
@@ -262,3 +263,777 @@ func TestDeliverSendsPercentageUpdates(t *testing.T) {
 		}
 	}
 }
+
+// TestDeliverEndpointInvalidPriority tests that the /deliver endpoint rejects unknown priorities.
+func TestDeliverEndpointInvalidPriority(t *testing.T) {
+	d := NewDelivery()
+	router := chi.NewRouter()
+	router.Post("/deliver", d.HandleDeliver)
+
+	req := DeliverRequest{
+		OrderID:    uuid.New(),
+		OrderItems: []OrderItem{{PizzaType: "Margherita", Quantity: 1}},
+		Priority:   "urgent",
+	}
+	body, _ := json.Marshal(req)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/deliver", bytes.NewReader(body)))
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, rr.Code)
+	}
+}
+
+// TestDeliverDispatchesHigherPriorityFirst tests that queued VIP and express orders get a driver before normal ones.
+func TestDeliverDispatchesHigherPriorityFirst(t *testing.T) {
+	delivered := make(chan uuid.UUID, 10)
+	storeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event OrderEvent
+		json.NewDecoder(r.Body).Decode(&event)
+		if event.Status == "DELIVERED" {
+			delivered <- event.OrderID
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer storeServer.Close()
+
+	d := NewDeliveryWithConfig(DeliveryConfig{
+		StoreURL:         storeServer.URL,
+		DeliveryTimeFunc: func() int { return 1 },
+		ReturnTimeFunc:   func(int) int { return 0 },
+		Drivers: map[string]*Driver{
+			"driver-1": {ID: "driver-1", Name: "Alice", VehicleType: VehicleBike, Status: DriverIdle},
+		},
+	})
+	router := chi.NewRouter()
+	router.Post("/deliver", d.HandleDeliver)
+
+	first, normal, express, vip := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	for _, r := range []struct {
+		orderID  uuid.UUID
+		priority string
+	}{
+		{first, ""},
+		{normal, PriorityNormal},
+		{express, PriorityExpress},
+		{vip, PriorityVIP},
+	} {
+		req := DeliverRequest{
+			OrderID:    r.orderID,
+			OrderItems: []OrderItem{{PizzaType: "Margherita", Quantity: 1}},
+			Priority:   r.priority,
+		}
+		body, _ := json.Marshal(req)
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/deliver", bytes.NewReader(body)))
+	}
+
+	for i, want := range []uuid.UUID{first, vip, express, normal} {
+		select {
+		case got := <-delivered:
+			if got != want {
+				t.Errorf("delivery %d: expected %s, got %s", i, want, got)
+			}
+		case <-time.After(6 * time.Second):
+			t.Fatalf("timed out waiting for delivery %d", i)
+		}
+	}
+}
+
+// TestGetDriversReturnsFleet tests that GET /drivers returns the default fleet ordered by ID.
+func TestGetDriversReturnsFleet(t *testing.T) {
+	d := NewDelivery()
+	router := chi.NewRouter()
+	router.Get("/drivers", d.HandleGetDrivers)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/drivers", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	var drivers []Driver
+	if err := json.NewDecoder(rr.Body).Decode(&drivers); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(drivers) != 4 {
+		t.Fatalf("expected 4 drivers, got %d", len(drivers))
+	}
+	for i, driver := range drivers {
+		if want := fmt.Sprintf("driver-%d", i+1); driver.ID != want {
+			t.Errorf("expected driver %d to be '%s', got '%s'", i, want, driver.ID)
+		}
+		if driver.Status != DriverIdle {
+			t.Errorf("expected driver '%s' to be idle, got '%s'", driver.ID, driver.Status)
+		}
+	}
+}
+
+// TestDeliverQueuesOrdersWhenNoDriverIsIdle tests that orders wait for a driver to return before being delivered.
+func TestDeliverQueuesOrdersWhenNoDriverIsIdle(t *testing.T) {
+	delivered := make(chan uuid.UUID, 10)
+	storeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event OrderEvent
+		json.NewDecoder(r.Body).Decode(&event)
+		if event.Status == "DELIVERED" {
+			delivered <- event.OrderID
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer storeServer.Close()
+
+	d := NewDeliveryWithConfig(DeliveryConfig{
+		StoreURL:         storeServer.URL,
+		DeliveryTimeFunc: func() int { return 1 },
+		ReturnTimeFunc:   func(int) int { return 1 },
+		Drivers: map[string]*Driver{
+			"driver-1": {ID: "driver-1", Name: "Alice", VehicleType: VehicleBike, Status: DriverIdle},
+			"driver-2": {ID: "driver-2", Name: "Bruno", VehicleType: VehicleCar, Status: DriverOffShift},
+		},
+	})
+	router := chi.NewRouter()
+	router.Post("/deliver", d.HandleDeliver)
+
+	post := func(orderID uuid.UUID) DeliverResponse {
+		req := DeliverRequest{OrderID: orderID, OrderItems: []OrderItem{{PizzaType: "Margherita", Quantity: 1}}}
+		body, _ := json.Marshal(req)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/deliver", bytes.NewReader(body)))
+		var resp DeliverResponse
+		json.NewDecoder(rr.Body).Decode(&resp)
+		return resp
+	}
+
+	first, second := uuid.New(), uuid.New()
+	if resp := post(first); resp.Status != "delivering" || resp.DriverID != "driver-1" {
+		t.Errorf("expected first order delivering with driver-1, got '%s' with '%s'", resp.Status, resp.DriverID)
+	}
+	// The only other driver is off shift, so the second order has to wait
+	if resp := post(second); resp.Status != "queued" || resp.DriverID != "" {
+		t.Errorf("expected second order queued without a driver, got '%s' with '%s'", resp.Status, resp.DriverID)
+	}
+
+	start := time.Now()
+	for _, want := range []uuid.UUID{first, second} {
+		select {
+		case got := <-delivered:
+			if got != want {
+				t.Errorf("expected %s to be delivered, got %s", want, got)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for deliveries")
+		}
+	}
+
+	// Second delivery only starts after the 1s delivery plus 1s return trip
+	if elapsed := time.Since(start); elapsed < 2500*time.Millisecond {
+		t.Errorf("expected second delivery to wait for the driver to return, took %s", elapsed)
+	}
+
+	var statuses []string
+	for _, driver := range d.Drivers() {
+		statuses = append(statuses, driver.Status)
+	}
+	if statuses[0] != DriverEnRoute && statuses[0] != DriverReturning {
+		t.Errorf("expected driver-1 to be on the road after its second delivery, got '%s'", statuses[0])
+	}
+	if statuses[1] != DriverOffShift {
+		t.Errorf("expected driver-2 to stay off shift, got '%s'", statuses[1])
+	}
+}
+
+// TestDeliverBatchesOrdersWithinWindow tests that orders arriving within the batch window share one driver's trip with a DELIVERED event per stop.
+func TestDeliverBatchesOrdersWithinWindow(t *testing.T) {
+	delivered := make(chan OrderEvent, 10)
+	storeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event OrderEvent
+		json.NewDecoder(r.Body).Decode(&event)
+		if event.Status == "DELIVERED" {
+			delivered <- event
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer storeServer.Close()
+
+	d := NewDeliveryWithConfig(DeliveryConfig{
+		StoreURL:         storeServer.URL,
+		DeliveryTimeFunc: func() int { return 1 },
+		ReturnTimeFunc:   func(int) int { return 0 },
+		BatchWindow:      1 * time.Second,
+	})
+	router := chi.NewRouter()
+	router.Post("/deliver", d.HandleDeliver)
+
+	start := time.Now()
+	first, second := uuid.New(), uuid.New()
+	for _, orderID := range []uuid.UUID{first, second} {
+		req := DeliverRequest{OrderID: orderID, OrderItems: []OrderItem{{PizzaType: "Margherita", Quantity: 1}}}
+		body, _ := json.Marshal(req)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/deliver", bytes.NewReader(body)))
+
+		var resp DeliverResponse
+		json.NewDecoder(rr.Body).Decode(&resp)
+		if resp.Status != "queued" {
+			t.Errorf("expected order to be held in the batch window, got status '%s'", resp.Status)
+		}
+	}
+
+	for i, want := range []uuid.UUID{first, second} {
+		select {
+		case event := <-delivered:
+			if event.OrderID != want {
+				t.Errorf("stop %d: expected %s, got %s", i+1, want, event.OrderID)
+			}
+			if event.Stop != i+1 {
+				t.Errorf("expected stop %d, got %d", i+1, event.Stop)
+			}
+			if event.DriverID != "driver-1" {
+				t.Errorf("expected both orders on driver-1's trip, got '%s'", event.DriverID)
+			}
+		case <-time.After(6 * time.Second):
+			t.Fatalf("timed out waiting for stop %d", i+1)
+		}
+	}
+
+	// The batch waits for the window to close before the one-second legs start
+	if elapsed := time.Since(start); elapsed < 3*time.Second {
+		t.Errorf("expected batch to be dispatched after the window, both stops delivered in %v", elapsed)
+	}
+}
+
+// TestDeliverDispatchesFullBatchImmediately tests that a batch leaves before its window closes once it is full.
+func TestDeliverDispatchesFullBatchImmediately(t *testing.T) {
+	storeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer storeServer.Close()
+
+	d := NewDeliveryWithConfig(DeliveryConfig{
+		StoreURL:         storeServer.URL,
+		DeliveryTimeFunc: func() int { return 1 },
+		ReturnTimeFunc:   func(int) int { return 0 },
+		BatchWindow:      time.Minute,
+		MaxBatchSize:     2,
+	})
+	router := chi.NewRouter()
+	router.Post("/deliver", d.HandleDeliver)
+
+	var resp DeliverResponse
+	for range 2 {
+		req := DeliverRequest{OrderID: uuid.New(), OrderItems: []OrderItem{{PizzaType: "Margherita", Quantity: 1}}}
+		body, _ := json.Marshal(req)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/deliver", bytes.NewReader(body)))
+		json.NewDecoder(rr.Body).Decode(&resp)
+	}
+
+	if resp.Status != "delivering" || resp.DriverID != "driver-1" {
+		t.Errorf("expected full batch delivering with driver-1, got '%s' with '%s'", resp.Status, resp.DriverID)
+	}
+	drivers := d.Drivers()
+	if len(drivers[0].OrderIDs) != 2 {
+		t.Errorf("expected driver-1 to carry 2 orders, got %d", len(drivers[0].OrderIDs))
+	}
+}
+
+// TestDeliverBatchRespectsMaxWait tests that an order is not added to a batch that would deliver it after the maximum wait.
+func TestDeliverBatchRespectsMaxWait(t *testing.T) {
+	delivered := make(chan OrderEvent, 10)
+	storeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event OrderEvent
+		json.NewDecoder(r.Body).Decode(&event)
+		if event.Status == "DELIVERED" {
+			delivered <- event
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer storeServer.Close()
+
+	d := NewDeliveryWithConfig(DeliveryConfig{
+		StoreURL:         storeServer.URL,
+		DeliveryTimeFunc: func() int { return 1 },
+		ReturnTimeFunc:   func(int) int { return 0 },
+		BatchWindow:      500 * time.Millisecond,
+		MaxWait:          2 * time.Second,
+		Drivers: map[string]*Driver{
+			"driver-1": {ID: "driver-1", Name: "Alice", VehicleType: VehicleBike, Status: DriverIdle},
+			"driver-2": {ID: "driver-2", Name: "Bruno", VehicleType: VehicleCar, Status: DriverIdle, UpdatedAt: time.Now()},
+		},
+	})
+	router := chi.NewRouter()
+	router.Post("/deliver", d.HandleDeliver)
+
+	// Waiting 0.5s plus two one-second legs exceeds the maximum wait of 2s for the second order
+	for range 2 {
+		req := DeliverRequest{OrderID: uuid.New(), OrderItems: []OrderItem{{PizzaType: "Margherita", Quantity: 1}}}
+		body, _ := json.Marshal(req)
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/deliver", bytes.NewReader(body)))
+	}
+
+	drivers := make(map[string]bool)
+	for i := 0; i < 2; i++ {
+		select {
+		case event := <-delivered:
+			if event.Stop != 1 {
+				t.Errorf("expected each order to be the only stop of its trip, got stop %d", event.Stop)
+			}
+			drivers[event.DriverID] = true
+		case <-time.After(6 * time.Second):
+			t.Fatalf("timed out waiting for delivery %d", i)
+		}
+	}
+	if len(drivers) != 2 {
+		t.Errorf("expected the orders to go with different drivers, got %v", drivers)
+	}
+}
+
+// TestDeliverEstimatesTimeFromAddress tests that the delivery time follows the distance to the address and is returned as an ETA.
+func TestDeliverEstimatesTimeFromAddress(t *testing.T) {
+	delivered := make(chan time.Time, 1)
+	storeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event OrderEvent
+		json.NewDecoder(r.Body).Decode(&event)
+		if event.Status == "DELIVERED" {
+			delivered <- time.Now()
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer storeServer.Close()
+
+	model := DefaultDeliveryModel()
+	d := NewDeliveryWithConfig(DeliveryConfig{
+		StoreURL:         storeServer.URL,
+		DeliveryTimeFunc: func() int { return 20 },
+		Model:            &model,
+	})
+	router := chi.NewRouter()
+	router.Post("/deliver", d.HandleDeliver)
+
+	address := &Address{Street: "Carrer de Mallorca 401", City: "Barcelona", Lat: model.Store.Lat + 0.01, Lon: model.Store.Lon}
+	want := model.travelTime(VehicleScooter, model.Store, address.coordinates())
+
+	start := time.Now()
+	req := DeliverRequest{OrderID: uuid.New(), OrderItems: []OrderItem{{PizzaType: "Margherita", Quantity: 1}}, DeliveryAddress: address}
+	body, _ := json.Marshal(req)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/deliver", bytes.NewReader(body)))
+
+	var resp DeliverResponse
+	json.NewDecoder(rr.Body).Decode(&resp)
+	if resp.EstimatedSeconds != want {
+		t.Errorf("expected estimate of %d seconds, got %d", want, resp.EstimatedSeconds)
+	}
+	if resp.ETA == nil || resp.ETA.Sub(start) < time.Duration(want)*time.Second {
+		t.Errorf("expected ETA %d seconds from now, got %v", want, resp.ETA)
+	}
+
+	select {
+	case at := <-delivered:
+		if elapsed := at.Sub(start).Round(time.Second); elapsed != time.Duration(want)*time.Second {
+			t.Errorf("expected delivery after %d seconds, took %v", want, elapsed)
+		}
+	case <-time.After(time.Duration(want+3) * time.Second):
+		t.Fatal("timed out waiting for delivery")
+	}
+}
+
+// TestDeliverEndpointInvalidAddress tests that addresses without a street or valid coordinates are rejected.
+func TestDeliverEndpointInvalidAddress(t *testing.T) {
+	d := NewDelivery()
+	router := chi.NewRouter()
+	router.Post("/deliver", d.HandleDeliver)
+
+	req := DeliverRequest{
+		OrderID:         uuid.New(),
+		OrderItems:      []OrderItem{{PizzaType: "Margherita", Quantity: 1}},
+		DeliveryAddress: &Address{Street: "Carrer de Mallorca 401", Lat: 41.4, Lon: 200},
+	}
+	body, _ := json.Marshal(req)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/deliver", bytes.NewReader(body)))
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, rr.Code)
+	}
+}
+
+// TestDeliverBatchesOnlyNearbyOrders tests that orders headed far from the head of a batch get their own trip.
+func TestDeliverBatchesOnlyNearbyOrders(t *testing.T) {
+	storeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer storeServer.Close()
+
+	model := DefaultDeliveryModel()
+	d := NewDeliveryWithConfig(DeliveryConfig{
+		StoreURL:     storeServer.URL,
+		BatchWindow:  time.Minute,
+		MaxBatchSize: 2,
+		Model:        &model,
+	})
+	router := chi.NewRouter()
+	router.Post("/deliver", d.HandleDeliver)
+
+	post := func(lat float64) DeliverResponse {
+		req := DeliverRequest{
+			OrderID:         uuid.New(),
+			OrderItems:      []OrderItem{{PizzaType: "Margherita", Quantity: 1}},
+			DeliveryAddress: &Address{Street: "Test street 1", Lat: lat, Lon: model.Store.Lon},
+		}
+		body, _ := json.Marshal(req)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/deliver", bytes.NewReader(body)))
+		var resp DeliverResponse
+		json.NewDecoder(rr.Body).Decode(&resp)
+		return resp
+	}
+
+	post(model.Store.Lat + 0.01)
+	// About 5.5 km away from the first order, outside the batch radius
+	if resp := post(model.Store.Lat - 0.04); resp.Status != "queued" {
+		t.Errorf("expected far order to stay queued, got '%s'", resp.Status)
+	}
+	// About 1 km away from the first order, so the batch is full and leaves
+	if resp := post(model.Store.Lat + 0.02); resp.Status != "delivering" {
+		t.Errorf("expected nearby order to complete the batch, got '%s'", resp.Status)
+	}
+}
+
+// TestDeliverSendsLocationUpdates tests that progress events carry the driver's location moving towards the address.
+func TestDeliverSendsLocationUpdates(t *testing.T) {
+	var mu sync.Mutex
+	var events []OrderEvent
+	storeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event OrderEvent
+		json.NewDecoder(r.Body).Decode(&event)
+		mu.Lock()
+		events = append(events, event)
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer storeServer.Close()
+
+	model := DefaultDeliveryModel()
+	d := NewDeliveryWithConfig(DeliveryConfig{StoreURL: storeServer.URL, Model: &model})
+	router := chi.NewRouter()
+	router.Post("/deliver", d.HandleDeliver)
+
+	// Due north of the store
+	address := &Address{Street: "Carrer de Mallorca 401", Lat: model.Store.Lat + 0.02, Lon: model.Store.Lon}
+	req := DeliverRequest{OrderID: uuid.New(), OrderItems: []OrderItem{{PizzaType: "Margherita", Quantity: 1}}, DeliveryAddress: address}
+	body, _ := json.Marshal(req)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/deliver", bytes.NewReader(body)))
+
+	var resp DeliverResponse
+	json.NewDecoder(rr.Body).Decode(&resp)
+	time.Sleep(time.Duration(resp.EstimatedSeconds)*time.Second + 500*time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(events) != resp.EstimatedSeconds+1 {
+		t.Fatalf("expected %d events, got %d", resp.EstimatedSeconds+1, len(events))
+	}
+
+	prevLat := model.Store.Lat
+	for i, event := range events {
+		if event.Location == nil {
+			t.Fatalf("event %d (%s) has no location", i, event.Status)
+		}
+		if event.Location.Lat < prevLat {
+			t.Errorf("event %d: expected driver to keep moving north, got lat %f after %f", i, event.Location.Lat, prevLat)
+		}
+		prevLat = event.Location.Lat
+		if event.Location.Heading != 0 {
+			t.Errorf("event %d: expected heading 0, got %.0f", i, event.Location.Heading)
+		}
+	}
+
+	for i, event := range events[:len(events)-1] {
+		if want := resp.EstimatedSeconds - i - 1; event.Location.ETASeconds != want {
+			t.Errorf("event %d: expected %d seconds left, got %d", i, want, event.Location.ETASeconds)
+		}
+	}
+
+	last := events[len(events)-1]
+	if last.Status != "DELIVERED" {
+		t.Errorf("expected last event to be DELIVERED, got '%s'", last.Status)
+	}
+	if last.Location.Lat != address.Lat || last.Location.Lon != address.Lon || last.Location.ETASeconds != 0 {
+		t.Errorf("expected DELIVERED at the address with no time left, got %+v", last.Location)
+	}
+}
+
+// TestDeliverReportsHandoverFailure tests that an order that cannot be handed over gets a DELIVERY_FAILED event with the reason.
+func TestDeliverReportsHandoverFailure(t *testing.T) {
+	events := make(chan OrderEvent, 10)
+	storeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event OrderEvent
+		json.NewDecoder(r.Body).Decode(&event)
+		events <- event
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer storeServer.Close()
+
+	d := NewDeliveryWithConfig(DeliveryConfig{
+		StoreURL:         storeServer.URL,
+		DeliveryTimeFunc: func() int { return 1 },
+		ReturnTimeFunc:   func(int) int { return 0 },
+		FailureRates:     map[string]float64{FailureCustomerNotHome: 1},
+	})
+	router := chi.NewRouter()
+	router.Post("/deliver", d.HandleDeliver)
+
+	req := DeliverRequest{OrderID: uuid.New(), OrderItems: []OrderItem{{PizzaType: "Margherita", Quantity: 1}}}
+	body, _ := json.Marshal(req)
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/deliver", bytes.NewReader(body)))
+
+	for {
+		select {
+		case event := <-events:
+			if event.Status == "DELIVERED" {
+				t.Fatal("expected the delivery to fail")
+			}
+			if event.Status != "DELIVERY_FAILED" {
+				continue
+			}
+			if event.Reason != FailureCustomerNotHome {
+				t.Errorf("expected reason '%s', got '%s'", FailureCustomerNotHome, event.Reason)
+			}
+			time.Sleep(100 * time.Millisecond)
+			for _, driver := range d.Drivers() {
+				if driver.Status != DriverIdle {
+					t.Errorf("expected driver '%s' to be back to idle, got '%s'", driver.ID, driver.Status)
+				}
+			}
+			return
+		case <-time.After(3 * time.Second):
+			t.Fatal("timed out waiting for DELIVERY_FAILED")
+		}
+	}
+}
+
+// TestDeliverVehicleBreakdownFailsOrdersOnBoard tests that a breakdown fails every undelivered order of the trip and takes the driver off shift.
+func TestDeliverVehicleBreakdownFailsOrdersOnBoard(t *testing.T) {
+	failed := make(chan OrderEvent, 10)
+	storeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event OrderEvent
+		json.NewDecoder(r.Body).Decode(&event)
+		if event.Status == "DELIVERY_FAILED" {
+			failed <- event
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer storeServer.Close()
+
+	d := NewDeliveryWithConfig(DeliveryConfig{
+		StoreURL:         storeServer.URL,
+		DeliveryTimeFunc: func() int { return 2 },
+		BatchWindow:      time.Minute,
+		MaxBatchSize:     2,
+		FailureRates:     map[string]float64{FailureVehicleBreakdown: 1},
+		Drivers: map[string]*Driver{
+			"driver-1": {ID: "driver-1", Name: "Alice", VehicleType: VehicleScooter, Status: DriverIdle},
+		},
+	})
+	router := chi.NewRouter()
+	router.Post("/deliver", d.HandleDeliver)
+
+	for range 2 {
+		req := DeliverRequest{OrderID: uuid.New(), OrderItems: []OrderItem{{PizzaType: "Margherita", Quantity: 1}}}
+		body, _ := json.Marshal(req)
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/deliver", bytes.NewReader(body)))
+	}
+
+	// The breakdown happens before the second stop, so at least that order fails
+	select {
+	case event := <-failed:
+		if event.Reason != FailureVehicleBreakdown {
+			t.Errorf("expected reason '%s', got '%s'", FailureVehicleBreakdown, event.Reason)
+		}
+	case <-time.After(6 * time.Second):
+		t.Fatal("timed out waiting for DELIVERY_FAILED")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if driver := d.Drivers()[0]; driver.Status != DriverOffShift || len(driver.OrderIDs) != 0 {
+		t.Errorf("expected driver off shift without orders, got '%s' with %d order(s)", driver.Status, len(driver.OrderIDs))
+	}
+}
+
+// TestDriverBackOnShiftAfterRepair tests that a driver whose vehicle broke down is idle again once
+// the repair time has passed.
+func TestDriverBackOnShiftAfterRepair(t *testing.T) {
+	failed := make(chan OrderEvent, 1)
+	storeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event OrderEvent
+		json.NewDecoder(r.Body).Decode(&event)
+		if event.Status == "DELIVERY_FAILED" {
+			failed <- event
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer storeServer.Close()
+
+	d := NewDeliveryWithConfig(DeliveryConfig{
+		StoreURL:         storeServer.URL,
+		DeliveryTimeFunc: func() int { return 1 },
+		FailureRates:     map[string]float64{FailureVehicleBreakdown: 1},
+		RepairTime:       300 * time.Millisecond,
+		Drivers: map[string]*Driver{
+			"driver-1": {ID: "driver-1", Name: "Alice", VehicleType: VehicleScooter, Status: DriverIdle},
+		},
+	})
+	router := chi.NewRouter()
+	router.Post("/deliver", d.HandleDeliver)
+
+	req := DeliverRequest{OrderID: uuid.New(), OrderItems: []OrderItem{{PizzaType: "Margherita", Quantity: 1}}}
+	body, _ := json.Marshal(req)
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/deliver", bytes.NewReader(body)))
+
+	select {
+	case <-failed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for DELIVERY_FAILED")
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		driver := d.Drivers()[0]
+		if driver.Status == DriverIdle {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the driver to be back on shift after the repair, got '%s'", driver.Status)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// TestGetProofAfterDelivery tests that a delivered order has a proof of delivery referenced by its DELIVERED event.
+func TestGetProofAfterDelivery(t *testing.T) {
+	delivered := make(chan OrderEvent, 1)
+	storeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event OrderEvent
+		json.NewDecoder(r.Body).Decode(&event)
+		if event.Status == "DELIVERED" {
+			delivered <- event
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer storeServer.Close()
+
+	model := DefaultDeliveryModel()
+	d := NewDeliveryWithConfig(DeliveryConfig{StoreURL: storeServer.URL, Model: &model})
+	router := chi.NewRouter()
+	router.Post("/deliver", d.HandleDeliver)
+	router.Get("/deliver/{orderId}/proof", d.HandleGetProof)
+
+	orderID := uuid.New()
+	address := &Address{Recipient: "Ada Lovelace", Street: "Carrer de Mallorca 401", Lat: model.Store.Lat + 0.005, Lon: model.Store.Lon}
+	req := DeliverRequest{OrderID: orderID, OrderItems: []OrderItem{{PizzaType: "Margherita", Quantity: 1}}, DeliveryAddress: address}
+	body, _ := json.Marshal(req)
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/deliver", bytes.NewReader(body)))
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/deliver/"+orderID.String()+"/proof", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected status %d before delivery, got %d", http.StatusNotFound, rr.Code)
+	}
+
+	var event OrderEvent
+	select {
+	case event = <-delivered:
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for delivery")
+	}
+	if event.ProofID == nil {
+		t.Fatal("expected DELIVERED event to reference the proof of delivery")
+	}
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/deliver/"+orderID.String()+"/proof", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	var proof ProofOfDelivery
+	if err := json.NewDecoder(rr.Body).Decode(&proof); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if proof.ID != *event.ProofID || proof.OrderID != orderID {
+		t.Errorf("expected proof %s of order %s, got %s of %s", *event.ProofID, orderID, proof.ID, proof.OrderID)
+	}
+	if proof.DriverID != event.DriverID || proof.RecipientName != "Ada Lovelace" {
+		t.Errorf("expected handover by '%s' to 'Ada Lovelace', got '%s' to '%s'", event.DriverID, proof.DriverID, proof.RecipientName)
+	}
+	if proof.DeliveredAt.IsZero() || len(proof.Signature) == 0 {
+		t.Error("expected a delivery timestamp and signature")
+	}
+	if proof.Location == nil || proof.Location.Lat != address.Lat || proof.Location.Lon != address.Lon {
+		t.Errorf("expected proof at the address, got %+v", proof.Location)
+	}
+}
+
+// TestGetProofInvalidOrderID tests that GET /deliver/{orderId}/proof rejects malformed order IDs.
+func TestGetProofInvalidOrderID(t *testing.T) {
+	d := NewDelivery()
+	router := chi.NewRouter()
+	router.Get("/deliver/{orderId}/proof", d.HandleGetProof)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/deliver/not-a-uuid/proof", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, rr.Code)
+	}
+}
+
+// TestQuoteReturnsZoneAndFee tests that POST /delivery/quote returns the zone, fee and delivery times of an address.
+func TestQuoteReturnsZoneAndFee(t *testing.T) {
+	d := NewDelivery()
+	router := chi.NewRouter()
+	router.Post("/delivery/quote", d.HandleQuote)
+
+	store := d.model.Store
+	req := QuoteRequest{DeliveryAddress: Address{Street: "Carrer de Mallorca 401", Lat: store.Lat + 0.03, Lon: store.Lon}}
+	body, _ := json.Marshal(req)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/delivery/quote", bytes.NewReader(body)))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+	var resp QuoteResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Zone != "city" || resp.Fee != 299 || resp.MaxDeliveryTime != 20 {
+		t.Errorf("expected zone 'city' with fee 299 within 20s, got %+v", resp)
+	}
+	if resp.EstimatedSeconds <= 0 || resp.EstimatedSeconds > resp.MaxDeliveryTime {
+		t.Errorf("expected an estimate within the zone's max delivery time, got %d", resp.EstimatedSeconds)
+	}
+}
+
+// TestQuoteOutsideZones tests that POST /delivery/quote rejects addresses outside every zone with 422.
+func TestQuoteOutsideZones(t *testing.T) {
+	d := NewDelivery()
+	router := chi.NewRouter()
+	router.Post("/delivery/quote", d.HandleQuote)
+
+	for _, tt := range []struct {
+		address Address
+		want    int
+	}{
+		{Address{Street: "Paseo de la Castellana 1", Lat: 40.4168, Lon: -3.7038}, http.StatusUnprocessableEntity},
+		{Address{Lat: 41.39, Lon: 2.17}, http.StatusBadRequest},
+	} {
+		body, _ := json.Marshal(QuoteRequest{DeliveryAddress: tt.address})
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/delivery/quote", bytes.NewReader(body)))
+		if rr.Code != tt.want {
+			t.Errorf("expected status %d for %+v, got %d", tt.want, tt.address, rr.Code)
+		}
+	}
+}
@@ -2,7 +2,11 @@
 // It handles delivering pizza orders by simulating delivery with progress updates.
 package delivery
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 // OrderItem represents a single item in an order, containing the pizza type
 // and the quantity requested.
@@ -11,16 +15,136 @@ type OrderItem struct {
 	Quantity  int    `json:"quantity"`
 }
 
+// Order priority constants, from lowest to highest.
+const (
+	PriorityNormal  = "normal"
+	PriorityExpress = "express"
+	PriorityVIP     = "vip"
+)
+
+// priorityLevels maps each priority to its rank in the dispatch queue.
+var priorityLevels = map[string]int{
+	PriorityNormal:  0,
+	PriorityExpress: 1,
+	PriorityVIP:     2,
+}
+
+// Address is the destination of a delivery. Lat and Lon are the customer's
+// coordinates in decimal degrees, used to estimate the delivery time, and
+// Recipient is the person the order is handed to.
+type Address struct {
+	Recipient  string  `json:"recipient,omitempty"`
+	Street     string  `json:"street"`
+	City       string  `json:"city,omitempty"`
+	PostalCode string  `json:"postalCode,omitempty"`
+	Lat        float64 `json:"lat"`
+	Lon        float64 `json:"lon"`
+}
+
+// Valid reports whether the address has a street and coordinates on the map.
+func (a Address) Valid() bool {
+	return a.Street != "" && a.Lat >= -90 && a.Lat <= 90 && a.Lon >= -180 && a.Lon <= 180
+}
+
+// coordinates returns the location of the address.
+func (a Address) coordinates() Coordinates {
+	return Coordinates{Lat: a.Lat, Lon: a.Lon}
+}
+
 // DeliverRequest represents the request body for delivering an order.
-// It contains the order ID and the items to be delivered.
+// It contains the order ID, the items to be delivered, the order priority and
+// the delivery address. Orders without an address take a random delivery time.
 type DeliverRequest struct {
-	OrderID    uuid.UUID   `json:"orderId"`
-	OrderItems []OrderItem `json:"orderItems"`
+	OrderID         uuid.UUID   `json:"orderId"`
+	OrderItems      []OrderItem `json:"orderItems"`
+	Priority        string      `json:"priority,omitempty"`
+	DeliveryAddress *Address    `json:"deliveryAddress,omitempty"`
 }
 
 // DeliverResponse represents the response returned after accepting a delivery request.
+// DriverID is set when a driver was assigned right away; otherwise the order is
+// queued until a driver becomes idle. ETA is the estimated delivery time; for
+// queued orders it assumes a driver leaves as soon as the order is dispatched.
 type DeliverResponse struct {
-	OrderID uuid.UUID `json:"orderId"`
-	Status  string    `json:"status"`
-	Message string    `json:"message,omitempty"`
+	OrderID          uuid.UUID  `json:"orderId"`
+	Status           string     `json:"status"`
+	DriverID         string     `json:"driverId,omitempty"`
+	EstimatedSeconds int        `json:"estimatedSeconds,omitempty"`
+	ETA              *time.Time `json:"eta,omitempty"`
+	Message          string     `json:"message,omitempty"`
+}
+
+// Delivery failure modes, sent as the reason of DELIVERY_FAILED events.
+const (
+	FailureCustomerNotHome  = "customer_not_home"
+	FailureAddressNotFound  = "address_not_found"
+	FailureVehicleBreakdown = "vehicle_breakdown"
+)
+
+// QuoteRequest represents the request body for quoting a delivery.
+type QuoteRequest struct {
+	DeliveryAddress Address `json:"deliveryAddress"`
+}
+
+// QuoteResponse is the delivery offer for an address: the zone it falls in,
+// the zone's fee in minor currency units and maximum delivery time, and the
+// estimated delivery time in seconds.
+type QuoteResponse struct {
+	Zone             string `json:"zone"`
+	Fee              int    `json:"fee"`
+	MaxDeliveryTime  int    `json:"maxDeliveryTime"`
+	EstimatedSeconds int    `json:"estimatedSeconds"`
+}
+
+// ProofOfDelivery records the handover of an order: when and where it was
+// delivered, by which driver and to whom. Signature and Photo are optional
+// blobs, sent base64-encoded in JSON.
+type ProofOfDelivery struct {
+	ID            uuid.UUID    `json:"id"`
+	OrderID       uuid.UUID    `json:"orderId"`
+	DeliveredAt   time.Time    `json:"deliveredAt"`
+	DriverID      string       `json:"driverId"`
+	RecipientName string       `json:"recipientName,omitempty"`
+	Signature     []byte       `json:"signature,omitempty"`
+	Photo         []byte       `json:"photo,omitempty"`
+	Location      *Coordinates `json:"location,omitempty"`
+}
+
+// Driver status constants.
+const (
+	DriverIdle      = "idle"
+	DriverEnRoute   = "en-route"
+	DriverReturning = "returning"
+	DriverOffShift  = "off-shift"
+)
+
+// Vehicle type constants.
+const (
+	VehicleBike    = "bike"
+	VehicleScooter = "scooter"
+	VehicleCar     = "car"
+)
+
+// Driver represents a delivery driver of the fleet with their current state.
+// OrderID is the first order of the trip the driver is on or returning from,
+// and OrderIDs lists all orders of that trip in delivery order.
+type Driver struct {
+	ID          string      `json:"id"`
+	Name        string      `json:"name"`
+	VehicleType string      `json:"vehicleType"`
+	Status      string      `json:"status"`
+	OrderID     *uuid.UUID  `json:"orderId,omitempty"`
+	OrderIDs    []uuid.UUID `json:"orderIds,omitempty"`
+	UpdatedAt   time.Time   `json:"updatedAt"`
+}
+
+// DefaultDrivers returns the default driver fleet, all idle.
+func DefaultDrivers() map[string]*Driver {
+	now := time.Now()
+	return map[string]*Driver{
+		"driver-1": {ID: "driver-1", Name: "Alice", VehicleType: VehicleScooter, Status: DriverIdle, UpdatedAt: now},
+		"driver-2": {ID: "driver-2", Name: "Bruno", VehicleType: VehicleBike, Status: DriverIdle, UpdatedAt: now},
+		"driver-3": {ID: "driver-3", Name: "Chiara", VehicleType: VehicleCar, Status: DriverIdle, UpdatedAt: now},
+		"driver-4": {ID: "driver-4", Name: "Dmitri", VehicleType: VehicleScooter, Status: DriverIdle, UpdatedAt: now},
+	}
 }
@@ -0,0 +1,51 @@
+package delivery
+
+import (
+	"fmt"
+	"html"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// recordProof records the proof of delivery for an order handed over at its
+// stop and returns it. When the recipient is known the driver captures their
+// signature; drivers do not take photos in the simulation.
+func (d *Delivery) recordProof(stop tripStop, driverID string) *ProofOfDelivery {
+	proof := &ProofOfDelivery{
+		ID:            uuid.New(),
+		OrderID:       stop.orderID,
+		DeliveredAt:   time.Now().UTC(),
+		DriverID:      driverID,
+		RecipientName: stop.recipient,
+		Location:      stop.destination,
+	}
+	if stop.recipient != "" {
+		proof.Signature = signature(stop.recipient)
+	}
+
+	d.mu.Lock()
+	d.proofs[stop.orderID] = proof
+	d.mu.Unlock()
+
+	slog.Info("proof of delivery recorded", "orderId", stop.orderID, "proofId", proof.ID, "driverId", driverID)
+	return proof
+}
+
+// signature returns a simulated signature of the recipient as an SVG image.
+func signature(recipient string) []byte {
+	return []byte(fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="240" height="60">`+
+		`<text x="10" y="40" font-family="cursive" font-size="24">%s</text></svg>`, html.EscapeString(recipient)))
+}
+
+// Proof returns the proof of delivery of an order.
+func (d *Delivery) Proof(orderID uuid.UUID) (ProofOfDelivery, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	proof, ok := d.proofs[orderID]
+	if !ok {
+		return ProofOfDelivery{}, false
+	}
+	return *proof, true
+}
@@ -0,0 +1,92 @@
+package delivery
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ErrOutsideZones is returned when an address is outside every delivery zone.
+var ErrOutsideZones = errors.New("address is outside the delivery zones")
+
+// Zone is an area the store delivers to, either a ring of RadiusKm around the
+// store or a Polygon of coordinates. Fee is the delivery fee in minor currency
+// units (cents), and MaxDeliveryTime the longest delivery promised in the zone,
+// in simulated seconds.
+type Zone struct {
+	Name            string        `json:"name"`
+	RadiusKm        float64       `json:"radiusKm,omitempty"`
+	Polygon         []Coordinates `json:"polygon,omitempty"`
+	Fee             int           `json:"fee"`
+	MaxDeliveryTime int           `json:"maxDeliveryTime"`
+}
+
+// contains reports whether the point lies in the zone around the store.
+func (z Zone) contains(store, point Coordinates) bool {
+	if len(z.Polygon) > 0 {
+		return inPolygon(z.Polygon, point)
+	}
+	return distanceKm(store, point) <= z.RadiusKm
+}
+
+// DefaultZones returns three radius rings around the store with increasing
+// fees and delivery times.
+func DefaultZones() []Zone {
+	return []Zone{
+		{Name: "center", RadiusKm: 2, Fee: 199, MaxDeliveryTime: 12},
+		{Name: "city", RadiusKm: 4, Fee: 299, MaxDeliveryTime: 20},
+		{Name: "outskirts", RadiusKm: 7, Fee: 499, MaxDeliveryTime: 30},
+	}
+}
+
+// LoadZones reads delivery zones from a JSON file containing an array of
+// zones. Zones are matched in file order, so inner rings must come first.
+func LoadZones(path string) ([]Zone, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading delivery zones: %w", err)
+	}
+
+	var zones []Zone
+	if err := json.Unmarshal(data, &zones); err != nil {
+		return nil, fmt.Errorf("parsing delivery zones: %w", err)
+	}
+
+	for _, z := range zones {
+		if z.Name == "" {
+			return nil, fmt.Errorf("delivery zone without name")
+		}
+		if z.RadiusKm <= 0 && len(z.Polygon) < 3 {
+			return nil, fmt.Errorf("delivery zone %s needs a radius or a polygon of at least 3 points", z.Name)
+		}
+		if z.Fee < 0 || z.MaxDeliveryTime <= 0 {
+			return nil, fmt.Errorf("invalid fee or max delivery time for zone %s", z.Name)
+		}
+	}
+	return zones, nil
+}
+
+// zoneFor returns the first zone containing the point.
+func (d *Delivery) zoneFor(point Coordinates) (Zone, error) {
+	for _, z := range d.zones {
+		if z.contains(d.model.Store, point) {
+			return z, nil
+		}
+	}
+	return Zone{}, ErrOutsideZones
+}
+
+// inPolygon reports whether the point lies inside the polygon using ray
+// casting. Polygons are small enough to treat coordinates as planar.
+func inPolygon(polygon []Coordinates, point Coordinates) bool {
+	inside := false
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		a, b := polygon[i], polygon[j]
+		if (a.Lat > point.Lat) != (b.Lat > point.Lat) &&
+			point.Lon < (b.Lon-a.Lon)*(point.Lat-a.Lat)/(b.Lat-a.Lat)+a.Lon {
+			inside = !inside
+		}
+	}
+	return inside
+}
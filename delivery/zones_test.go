@@ -0,0 +1,79 @@
+package delivery
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestZoneForMatchesInnerRingFirst tests that addresses fall into the innermost ring containing them.
+func TestZoneForMatchesInnerRingFirst(t *testing.T) {
+	d := NewDelivery()
+	store := d.model.Store
+
+	tests := []struct {
+		lat  float64
+		want string
+	}{
+		{store.Lat + 0.01, "center"},    // about 1.1 km
+		{store.Lat + 0.03, "city"},      // about 3.3 km
+		{store.Lat + 0.05, "outskirts"}, // about 5.6 km
+	}
+	for _, tt := range tests {
+		zone, err := d.zoneFor(Coordinates{Lat: tt.lat, Lon: store.Lon})
+		if err != nil {
+			t.Fatalf("expected %.2f to be in a zone: %v", tt.lat, err)
+		}
+		if zone.Name != tt.want {
+			t.Errorf("expected zone '%s' for %.2f, got '%s'", tt.want, tt.lat, zone.Name)
+		}
+	}
+
+	if _, err := d.zoneFor(Coordinates{Lat: store.Lat + 0.1, Lon: store.Lon}); err != ErrOutsideZones {
+		t.Errorf("expected ErrOutsideZones 11 km away, got %v", err)
+	}
+}
+
+// TestPolygonZone tests that polygon zones contain the points inside their outline only.
+func TestPolygonZone(t *testing.T) {
+	zone := Zone{Name: "square", Polygon: []Coordinates{
+		{Lat: 41.0, Lon: 2.0},
+		{Lat: 41.0, Lon: 2.1},
+		{Lat: 41.1, Lon: 2.1},
+		{Lat: 41.1, Lon: 2.0},
+	}}
+
+	if !zone.contains(Coordinates{}, Coordinates{Lat: 41.05, Lon: 2.05}) {
+		t.Error("expected the center of the square to be in the zone")
+	}
+	if zone.contains(Coordinates{}, Coordinates{Lat: 41.15, Lon: 2.05}) {
+		t.Error("expected a point north of the square to be outside the zone")
+	}
+	if zone.contains(Coordinates{}, Coordinates{Lat: 41.05, Lon: 1.95}) {
+		t.Error("expected a point west of the square to be outside the zone")
+	}
+}
+
+// TestLoadZones tests reading zones from a JSON file and rejecting invalid ones.
+func TestLoadZones(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "zones.json")
+	os.WriteFile(valid, []byte(`[
+		{"name": "near", "radiusKm": 1.5, "fee": 0, "maxDeliveryTime": 10},
+		{"name": "harbour", "polygon": [{"lat": 41.37, "lon": 2.17}, {"lat": 41.37, "lon": 2.19}, {"lat": 41.36, "lon": 2.18}], "fee": 350, "maxDeliveryTime": 25}
+	]`), 0o644)
+
+	zones, err := LoadZones(valid)
+	if err != nil {
+		t.Fatalf("failed to load zones: %v", err)
+	}
+	if len(zones) != 2 || zones[1].Name != "harbour" || len(zones[1].Polygon) != 3 || zones[1].Fee != 350 {
+		t.Errorf("unexpected zones: %+v", zones)
+	}
+
+	invalid := filepath.Join(dir, "invalid.json")
+	os.WriteFile(invalid, []byte(`[{"name": "nowhere", "fee": 100, "maxDeliveryTime": 10}]`), 0o644)
+	if _, err := LoadZones(invalid); err == nil {
+		t.Error("expected an error for a zone without radius or polygon")
+	}
+}
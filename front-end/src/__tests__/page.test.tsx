@@ -9,6 +9,7 @@ global.fetch = jest.fn();
 function createMockWebSocket() {
   const mockWs = {
     close: jest.fn(),
+    send: jest.fn(),
     addEventListener: jest.fn(),
     removeEventListener: jest.fn(),
     readyState: 1,
@@ -149,6 +150,126 @@ describe('Home Page', () => {
     });
   });
 
+  it('subscribes to the placed order over the WebSocket', async () => {
+    const user = userEvent.setup();
+    const { mockWs } = createMockWebSocket();
+
+    (global.fetch as jest.Mock).mockResolvedValueOnce({
+      ok: true,
+      json: async () => ({ orderId: 'subscribe-test-id', orderStatus: 'pending' }),
+    });
+
+    render(<Home />);
+
+    await addPizzaToCart(user, 'Margherita');
+
+    const submitButton = screen.getByRole('button', { name: /place order/i });
+    await user.click(submitButton);
+
+    await waitFor(() => {
+      expect(mockWs.send).toHaveBeenCalledWith(
+        JSON.stringify({ action: 'subscribe', orderIds: ['subscribe-test-id'] })
+      );
+    });
+  });
+
+  it('reconnects with the last event ID and subscribes again when the connection drops', async () => {
+    const user = userEvent.setup();
+    const { mockWs, MockWebSocket } = createMockWebSocket();
+
+    (global.fetch as jest.Mock).mockResolvedValueOnce({
+      ok: true,
+      json: async () => ({ orderId: 'reconnect-test-id', orderStatus: 'pending' }),
+    });
+
+    render(<Home />);
+
+    await addPizzaToCart(user, 'Margherita');
+
+    const submitButton = screen.getByRole('button', { name: /place order/i });
+    await user.click(submitButton);
+
+    await waitFor(() => {
+      expect(mockWs.send).toHaveBeenCalledTimes(1);
+    });
+
+    await act(async () => {
+      mockWs.onmessage?.(new MessageEvent('message', {
+        data: JSON.stringify({
+          id: 7,
+          orderId: 'reconnect-test-id',
+          status: 'cooking',
+          source: 'kitchen',
+          timestamp: '2026-01-26T10:00:00Z',
+        }),
+      }));
+      mockWs.onclose?.(new CloseEvent('close'));
+    });
+
+    await waitFor(() => {
+      expect(MockWebSocket).toHaveBeenCalledTimes(2);
+    }, { timeout: 3000 });
+    expect(MockWebSocket.mock.calls[1][0] as string).toContain('lastEventId=7');
+
+    await waitFor(() => {
+      expect(mockWs.send).toHaveBeenCalledTimes(2);
+    });
+    expect(mockWs.send).toHaveBeenLastCalledWith(
+      JSON.stringify({ action: 'subscribe', orderIds: ['reconnect-test-id'] })
+    );
+  });
+
+  it('keeps events from a restarted store and resumes from its stream', async () => {
+    const user = userEvent.setup();
+    const { mockWs, MockWebSocket } = createMockWebSocket();
+
+    (global.fetch as jest.Mock).mockResolvedValueOnce({
+      ok: true,
+      json: async () => ({ orderId: 'restart-test-id', orderStatus: 'pending' }),
+    });
+
+    render(<Home />);
+
+    await addPizzaToCart(user, 'Margherita');
+
+    const submitButton = screen.getByRole('button', { name: /place order/i });
+    await user.click(submitButton);
+
+    await waitFor(() => {
+      expect(mockWs.send).toHaveBeenCalledTimes(1);
+    });
+
+    const sendEvent = (id: number, stream: string, status: string) =>
+      mockWs.onmessage?.(new MessageEvent('message', {
+        data: JSON.stringify({
+          id,
+          stream,
+          orderId: 'restart-test-id',
+          status,
+          source: 'kitchen',
+          timestamp: '2026-01-26T10:00:00Z',
+        }),
+      }));
+
+    await act(async () => {
+      sendEvent(7, 'before-restart', 'cooking');
+      sendEvent(7, 'before-restart', 'cooking');
+      sendEvent(1, 'after-restart', 'COOKED');
+    });
+
+    expect(screen.getAllByText('cooking')).toHaveLength(1);
+    expect(screen.getByText('COOKED')).toBeInTheDocument();
+
+    await act(async () => {
+      mockWs.onclose?.(new CloseEvent('close'));
+    });
+
+    await waitFor(() => {
+      expect(MockWebSocket).toHaveBeenCalledTimes(2);
+    }, { timeout: 3000 });
+    expect(MockWebSocket.mock.calls[1][0] as string).toContain('lastEventId=after-restart:1');
+  });
+
   it('displays WebSocket connection indicator', async () => {
     const user = userEvent.setup();
     createMockWebSocket();
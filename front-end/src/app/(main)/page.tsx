@@ -14,6 +14,8 @@ interface OrderItem {
 }
 
 interface WebSocketEvent {
+  id?: number;
+  stream?: string;
   orderId: string;
   status: string;
   source: string;
@@ -47,6 +49,8 @@ const PIZZAS = [
   },
 ];
 
+const RECONNECT_DELAY_MS = 1000;
+
 function generateClientId(): string {
   return 'client-' + Math.random().toString(36).substring(2, 15);
 }
@@ -66,6 +70,10 @@ export default function Home() {
   const [orderDelivered, setOrderDelivered] = useState(true);
   const wsRef = useRef<WebSocket | null>(null);
   const clientIdRef = useRef<string>(generateClientId());
+  const lastEventIdRef = useRef(0);
+  const streamRef = useRef<string | null>(null);
+  const followedOrderRef = useRef<string | null>(null);
+  const unmountedRef = useRef(false);
 
   const totalQuantity = cart.reduce((sum, item) => sum + item.quantity, 0);
   const totalPrice = cart.reduce(
@@ -73,27 +81,56 @@ export default function Home() {
     0
   );
 
-  const connectWebSocket = useCallback((): Promise<void> => {
+  // Connects with the stream and ID of the last event received, so the store
+  // replays the events missed while disconnected once the followed order is
+  // subscribed to.
+  const connectWebSocket = useCallback(function connect(): Promise<void> {
     return new Promise((resolve, reject) => {
       const storeWsUrl = process.env.NEXT_PUBLIC_STORE_WS_URL || 'ws://localhost:8080';
-      const wsUrl = `${storeWsUrl}/ws?clientId=${clientIdRef.current}`;
+      const lastEventId = streamRef.current
+        ? `${streamRef.current}:${lastEventIdRef.current}`
+        : `${lastEventIdRef.current}`;
+      const wsUrl = `${storeWsUrl}/ws?clientId=${clientIdRef.current}&lastEventId=${lastEventId}`;
       const ws = new WebSocket(wsUrl);
 
       ws.onopen = () => {
         setWsConnected(true);
+        if (followedOrderRef.current) {
+          ws.send(
+            JSON.stringify({ action: 'subscribe', orderIds: [followedOrderRef.current] })
+          );
+        }
         resolve();
       };
 
       ws.onmessage = (event: MessageEvent) => {
         const data: WebSocketEvent = JSON.parse(event.data);
+        if (data.id !== undefined) {
+          // A restarted store numbers its events from 1 again in a new stream
+          if (data.stream !== undefined && data.stream !== streamRef.current) {
+            streamRef.current = data.stream;
+            lastEventIdRef.current = 0;
+          }
+          if (data.id <= lastEventIdRef.current) {
+            return;
+          }
+          lastEventIdRef.current = data.id;
+        }
         setEvents((prev) => [...prev, data]);
         if (data.status === 'DELIVERED') {
+          followedOrderRef.current = null;
           setOrderDelivered(true);
         }
       };
 
       ws.onclose = () => {
         setWsConnected(false);
+        // Reconnect while an order is followed, unless a new connection replaced this one
+        if (!unmountedRef.current && followedOrderRef.current && wsRef.current === ws) {
+          setTimeout(() => {
+            connect().catch(() => {});
+          }, RECONNECT_DELAY_MS);
+        }
       };
 
       ws.onerror = () => {
@@ -106,7 +143,9 @@ export default function Home() {
   }, []);
 
   useEffect(() => {
+    unmountedRef.current = false;
     return () => {
+      unmountedRef.current = true;
       if (wsRef.current) {
         wsRef.current.close();
       }
@@ -170,6 +209,10 @@ export default function Home() {
 
       if (response.ok) {
         const data = await response.json();
+        followedOrderRef.current = data.orderId;
+        wsRef.current?.send(
+          JSON.stringify({ action: 'subscribe', orderIds: [data.orderId] })
+        );
         setOrderId(data.orderId);
         setMessage('Order placed successfully!');
         setIsError(false);
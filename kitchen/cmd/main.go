@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
@@ -24,8 +25,26 @@ func main() {
 		port = "8081"
 	}
 
-	// Create kitchen instance
-	k := kitchen.NewKitchen()
+	// Load cooking profiles from a JSON file when one is configured
+	var profiles map[string]kitchen.CookingProfile
+	if path := os.Getenv("KITCHEN_PROFILES"); path != "" {
+		var err error
+		profiles, err = kitchen.LoadCookingProfiles(path)
+		if err != nil {
+			slog.Error("failed to load cooking profiles", "path", path, "error", err)
+			os.Exit(1)
+		}
+		slog.Info("cooking profiles loaded", "path", path, "count", len(profiles))
+	}
+
+	// Create kitchen instance with capacity from the environment
+	k := kitchen.NewKitchenWithConfig(kitchen.KitchenConfig{
+		Stations:     envInt("KITCHEN_STATIONS", kitchen.DefaultStations),
+		QueueSize:    envInt("KITCHEN_QUEUE_SIZE", kitchen.DefaultQueueSize),
+		Profiles:     profiles,
+		BurnRate:     envFloat("KITCHEN_BURN_RATE", 0),
+		JobRetention: time.Duration(envInt("KITCHEN_JOB_RETENTION", int(kitchen.DefaultJobRetention.Seconds()))) * time.Second,
+	})
 
 	// Set up router with middleware
 	r := chi.NewRouter()
@@ -34,6 +53,10 @@ func main() {
 
 	// Register routes
 	r.Post("/cook", k.HandleCook)
+	r.Get("/cook", k.HandleGetJobs)
+	r.Post("/cook/estimate", k.HandleEstimate)
+	r.Get("/cook/{orderId}", k.HandleGetJob)
+	r.Put("/cook/{orderId}", k.HandleUpdateCook)
 
 	// Health check endpoint
 	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
@@ -61,6 +84,7 @@ func main() {
 
 	<-ctx.Done()
 	slog.Info("shutting down kitchen service")
+	k.Stop()
 
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -71,3 +95,33 @@ func main() {
 	}
 	slog.Info("kitchen service stopped")
 }
+
+// envInt reads an integer from the environment, falling back to def when the
+// variable is unset or invalid.
+func envInt(key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		slog.Warn("invalid integer in environment, using default", "key", key, "value", v, "default", def)
+		return def
+	}
+	return n
+}
+
+// envFloat reads a floating point number from the environment, falling back to
+// def when the variable is unset or invalid.
+func envFloat(key string, def float64) float64 {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		slog.Warn("invalid number in environment, using default", "key", key, "value", v, "default", def)
+		return def
+	}
+	return f
+}
@@ -0,0 +1,109 @@
+package kitchen
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// DefaultBakeEstimate is the bake time, in seconds, expected for pizza types
+// without a cooking profile.
+const DefaultBakeEstimate = 6
+
+// CookEstimate is the kitchen's estimate of how long an order would wait for
+// a cook station and take to cook, in seconds.
+type CookEstimate struct {
+	WaitSeconds  int `json:"waitSeconds"`
+	CookSeconds  int `json:"cookSeconds"`
+	ReadySeconds int `json:"readySeconds"`
+}
+
+// expectedSeconds returns how long a pizza is expected to take across all of
+// its stages, taking the bake time its cooking profile aims for.
+func (k *Kitchen) expectedSeconds(pizzaType string) int {
+	profile, ok := k.profiles[pizzaType]
+	if !ok {
+		return DefaultBakeEstimate
+	}
+	return profile.PrepTime + profile.BakeTime + profile.RestTime
+}
+
+// estimate works out how long an order with the given items and priority
+// would take if it were queued now. It waits for the remaining work of the
+// orders being cooked and of the queued orders ranked at or above it, shared
+// by all stations, and its pizzas are cooked in parallel on the stations.
+func (k *Kitchen) estimate(req CookRequest) CookEstimate {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	var work, longest, pizzas int
+	for _, item := range req.OrderItems {
+		seconds := k.expectedSeconds(item.PizzaType)
+		work += seconds * item.Quantity
+		pizzas += item.Quantity
+		longest = max(longest, seconds)
+	}
+	cook := longest
+	if pizzas > 0 {
+		stations := min(pizzas, k.stations)
+		cook = max(longest, (work+stations-1)/stations)
+	}
+
+	now := time.Now()
+	level := float64(priorityLevels[req.Priority])
+	var ahead, queued int
+	for _, job := range k.jobs {
+		switch {
+		case job.status == JobCooking:
+			ahead += job.totalSeconds - job.doneSeconds
+		case job.status == JobQueued && k.rank(job, now) >= level:
+			ahead += job.totalSeconds
+			queued++
+		}
+	}
+	wait := 0
+	if queued > 0 || k.busy >= k.stations {
+		wait = (ahead + k.stations - 1) / k.stations
+	}
+
+	return CookEstimate{WaitSeconds: wait, CookSeconds: cook, ReadySeconds: wait + cook}
+}
+
+// HandleEstimate handles POST /cook/estimate requests. It returns how long an
+// order with the given items and priority would wait for a cook station and
+// take to cook if it were sent to the kitchen now, without queueing it.
+func (k *Kitchen) HandleEstimate(w http.ResponseWriter, r *http.Request) {
+	var req CookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if len(req.OrderItems) == 0 {
+		http.Error(w, "Order must contain at least one item", http.StatusBadRequest)
+		return
+	}
+	if !validQuantities(req.OrderItems) {
+		http.Error(w, "Item quantity must be positive", http.StatusBadRequest)
+		return
+	}
+
+	if req.Priority == "" {
+		req.Priority = PriorityNormal
+	}
+	if _, ok := priorityLevels[req.Priority]; !ok {
+		http.Error(w, "Invalid priority", http.StatusBadRequest)
+		return
+	}
+
+	estimate := k.estimate(req)
+	slog.Info("cooking estimated", "items", len(req.OrderItems), "priority", req.Priority, "readySeconds", estimate.ReadySeconds)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(estimate); err != nil {
+		slog.Error("failed to encode cook estimate", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,89 @@
+package kitchen
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// estimateCook sends a cook estimate request to the router and decodes the estimate.
+func estimateCook(t *testing.T, router http.Handler, req CookRequest) CookEstimate {
+	t.Helper()
+	body, _ := json.Marshal(req)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/cook/estimate", bytes.NewReader(body)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	var estimate CookEstimate
+	json.NewDecoder(rr.Body).Decode(&estimate)
+	return estimate
+}
+
+// TestEstimateFollowsProfilesAndQueue tests that the cook estimate spreads an order's pizzas over
+// the stations and waits for the work being cooked and queued ahead of the order's priority.
+func TestEstimateFollowsProfilesAndQueue(t *testing.T) {
+	storeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer storeServer.Close()
+
+	kitchen := NewKitchenWithConfig(KitchenConfig{
+		StoreURL: storeServer.URL,
+		Profiles: map[string]CookingProfile{
+			"Margherita": {PizzaType: "Margherita", PrepTime: 1, BakeTime: 2, RestTime: 1},
+		},
+		Stations: 2,
+	})
+	defer kitchen.Stop()
+
+	router := chi.NewRouter()
+	router.Post("/cook", kitchen.HandleCook)
+	router.Post("/cook/estimate", kitchen.HandleEstimate)
+
+	three := []OrderItem{{PizzaType: "Margherita", Quantity: 3}}
+	if estimate := estimateCook(t, router, CookRequest{OrderItems: three}); estimate != (CookEstimate{WaitSeconds: 0, CookSeconds: 6, ReadySeconds: 6}) {
+		t.Errorf("expected 3 pizzas to take 6 seconds on 2 idle stations, got %+v", estimate)
+	}
+
+	postCook(router, uuid.New())
+	postCook(router, uuid.New())
+	// Let both stations pick up an order before queueing a third
+	time.Sleep(100 * time.Millisecond)
+	postCook(router, uuid.New())
+
+	one := []OrderItem{{PizzaType: "Margherita", Quantity: 1}}
+	if estimate := estimateCook(t, router, CookRequest{OrderItems: one}); estimate.WaitSeconds != 6 || estimate.ReadySeconds != 10 {
+		t.Errorf("expected a normal order to wait 6 seconds, got %+v", estimate)
+	}
+	if estimate := estimateCook(t, router, CookRequest{OrderItems: one, Priority: PriorityVIP}); estimate.WaitSeconds != 4 || estimate.ReadySeconds != 8 {
+		t.Errorf("expected a VIP order to wait 4 seconds, got %+v", estimate)
+	}
+}
+
+// TestEstimateInvalidRequest tests that the estimate endpoint rejects empty orders and unknown priorities.
+func TestEstimateInvalidRequest(t *testing.T) {
+	kitchen := NewKitchen()
+	defer kitchen.Stop()
+
+	router := chi.NewRouter()
+	router.Post("/cook/estimate", kitchen.HandleEstimate)
+
+	for _, req := range []CookRequest{
+		{},
+		{OrderItems: []OrderItem{{PizzaType: "Margherita", Quantity: 1}}, Priority: "urgent"},
+	} {
+		body, _ := json.Marshal(req)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/cook/estimate", bytes.NewReader(body)))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status %d for %+v, got %d", http.StatusBadRequest, req, rr.Code)
+		}
+	}
+}
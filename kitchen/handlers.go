@@ -6,64 +6,189 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"math/rand"
 	"net/http"
+	"strconv"
+	"sync"
 	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 )
 
+// Default kitchen capacity settings.
+const (
+	DefaultStations   = 4
+	DefaultQueueSize  = 50
+	DefaultRetryAfter = 10 * time.Second
+
+	// DefaultAgingInterval is how long an order waits before it is ranked
+	// one priority level higher, so normal orders are not starved.
+	DefaultAgingInterval = 30 * time.Second
+
+	// DefaultJobRetention is how long a cooked or failed order can still be
+	// looked up before the kitchen forgets it.
+	DefaultJobRetention = 10 * time.Minute
+)
+
 // KitchenConfig contains configuration options for the Kitchen service.
 type KitchenConfig struct {
 	StoreURL        string
-	CookingTimeFunc func() int // Returns cooking time in seconds for each item
+	CookingTimeFunc func() int                // Returns baking time in seconds for pizzas without a cooking profile
+	Profiles        map[string]CookingProfile // Cooking profiles by pizza type
+	Stations        int                       // Number of cook stations working in parallel
+	QueueSize       int                       // Maximum number of orders waiting for a free station
+	RetryAfter      time.Duration             // Retry-After hint returned when the queue is full
+	BurnRate        float64                   // Probability between 0 and 1 that a pizza burns in the oven
+	AgingInterval   time.Duration             // Waiting time after which a queued order gains one priority level
+	JobRetention    time.Duration             // How long cooked and failed orders are kept after they finish
 }
 
 // OrderEvent represents an event sent to the store service.
 type OrderEvent struct {
-	OrderID uuid.UUID `json:"orderId"`
-	Status  string    `json:"status"`
-	Source  string    `json:"source"`
+	OrderID  uuid.UUID `json:"orderId"`
+	Status   string    `json:"status"`
+	Source   string    `json:"source"`
+	Reason   string    `json:"reason,omitempty"`
+	Stage    string    `json:"stage,omitempty"`
+	Progress int       `json:"progress,omitempty"` // percentage of the order's total cooking time
 }
 
 // Kitchen manages pizza cooking operations and provides HTTP handlers for the kitchen service.
+// Cook requests are placed in a queue ordered by priority and cooked by a fixed number of
+// cook stations. Orders of the same priority are cooked in FIFO order.
 type Kitchen struct {
+	rngMu           sync.Mutex
 	rng             *rand.Rand
 	storeURL        string
 	httpClient      *http.Client
 	cookingTimeFunc func() int
+	profiles        map[string]CookingProfile
+	retryAfter      time.Duration
+	burnRate        float64
+	agingInterval   time.Duration
+	jobRetention    time.Duration
+
+	mu        sync.Mutex
+	cond      *sync.Cond
+	queue     []*cookJob
+	jobs      map[uuid.UUID]*cookJob
+	stations  int
+	busy      int
+	queueSize int
+	stopped   bool
+	ctx       context.Context
+	cancel    context.CancelFunc
 }
 
-// NewKitchen creates a new Kitchen instance with a seeded random number generator.
+// NewKitchen creates a new Kitchen instance with a seeded random number generator
+// and the default number of cook stations.
 func NewKitchen() *Kitchen {
-	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
-	return &Kitchen{
-		rng:      rng,
+	return NewKitchenWithConfig(KitchenConfig{})
+}
+
+// NewKitchenWithConfig creates a new Kitchen instance with the given configuration
+// and starts its cook stations. The default cooking profiles are used unless
+// Profiles is set; setting only CookingTimeFunc disables profiles so every pizza
+// is baked for the time it returns.
+func NewKitchenWithConfig(config KitchenConfig) *Kitchen {
+	ctx, cancel := context.WithCancel(context.Background())
+	k := &Kitchen{
+		rng:      rand.New(rand.NewSource(time.Now().UnixNano())),
 		storeURL: "http://store:8080",
 		httpClient: &http.Client{
 			Timeout: 10 * time.Second,
 		},
-		cookingTimeFunc: func() int { return rng.Intn(10) + 1 },
+		retryAfter:    DefaultRetryAfter,
+		agingInterval: DefaultAgingInterval,
+		jobRetention:  DefaultJobRetention,
+		stations:      DefaultStations,
+		queueSize:     DefaultQueueSize,
+		jobs:          make(map[uuid.UUID]*cookJob),
+		ctx:           ctx,
+		cancel:        cancel,
 	}
-}
+	k.cond = sync.NewCond(&k.mu)
+	k.cookingTimeFunc = func() int { return k.randIntn(10) + 1 }
 
-// NewKitchenWithConfig creates a new Kitchen instance with the given configuration.
-func NewKitchenWithConfig(config KitchenConfig) *Kitchen {
-	k := NewKitchen()
 	if config.StoreURL != "" {
 		k.storeURL = config.StoreURL
 	}
 	if config.CookingTimeFunc != nil {
 		k.cookingTimeFunc = config.CookingTimeFunc
 	}
+	switch {
+	case config.Profiles != nil:
+		k.profiles = config.Profiles
+	case config.CookingTimeFunc != nil:
+		k.profiles = map[string]CookingProfile{}
+	default:
+		k.profiles = DefaultCookingProfiles()
+	}
+	if config.Stations > 0 {
+		k.stations = config.Stations
+	}
+	if config.QueueSize > 0 {
+		k.queueSize = config.QueueSize
+	}
+	if config.RetryAfter > 0 {
+		k.retryAfter = config.RetryAfter
+	}
+	if config.BurnRate > 0 {
+		k.burnRate = config.BurnRate
+	}
+	if config.AgingInterval > 0 {
+		k.agingInterval = config.AgingInterval
+	}
+	if config.JobRetention > 0 {
+		k.jobRetention = config.JobRetention
+	}
+
+	k.startStations()
 	return k
 }
 
+// randIntn returns a random number in [0, n) from the kitchen's generator.
+// It is safe to call from multiple cook stations.
+func (k *Kitchen) randIntn(n int) int {
+	k.rngMu.Lock()
+	defer k.rngMu.Unlock()
+	return k.rng.Intn(n)
+}
+
+// randFloat64 returns a random number in [0.0, 1.0) from the kitchen's generator.
+func (k *Kitchen) randFloat64() float64 {
+	k.rngMu.Lock()
+	defer k.rngMu.Unlock()
+	return k.rng.Float64()
+}
+
+// randNormFloat64 returns a standard normally distributed number from the
+// kitchen's generator.
+func (k *Kitchen) randNormFloat64() float64 {
+	k.rngMu.Lock()
+	defer k.rngMu.Unlock()
+	return k.rng.NormFloat64()
+}
+
+// validQuantities reports whether every item of an order has at least one
+// pizza.
+func validQuantities(items []OrderItem) bool {
+	for _, item := range items {
+		if item.Quantity <= 0 {
+			return false
+		}
+	}
+	return true
+}
+
 // HandleCook handles POST /cook requests to cook pizza order items.
-// It validates the request and starts cooking the items asynchronously.
-// Each item takes a random time from 1 to 10 seconds to cook.
+// It validates the request and places it in the kitchen queue, returning the
+// order's position in the queue. When the queue is full it responds with
+// 503 Service Unavailable and a Retry-After header.
 func (k *Kitchen) HandleCook(w http.ResponseWriter, r *http.Request) {
 	var req CookRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -76,17 +201,49 @@ func (k *Kitchen) HandleCook(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Order must contain at least one item", http.StatusBadRequest)
 		return
 	}
+	if !validQuantities(req.OrderItems) {
+		http.Error(w, "Item quantity must be positive", http.StatusBadRequest)
+		return
+	}
 
-	slog.Info("cook request received", "orderId", req.OrderID, "items", len(req.OrderItems))
+	if req.Priority == "" {
+		req.Priority = PriorityNormal
+	}
+	if _, ok := priorityLevels[req.Priority]; !ok {
+		http.Error(w, "Invalid priority", http.StatusBadRequest)
+		return
+	}
+
+	slog.Info("cook request received", "orderId", req.OrderID, "items", len(req.OrderItems), "priority", req.Priority)
 
-	// Start cooking in a goroutine (background; detach from request context)
-	go k.cookItems(context.Background(), req.OrderID, req.OrderItems)
+	// Queue the order for the next free cook station
+	position, err := k.enqueue(k.newCookJob(req))
+	if errors.Is(err, ErrJobActive) {
+		slog.Warn("cook request rejected", "orderId", req.OrderID, "error", err)
+		http.Error(w, "Order is already being cooked", http.StatusConflict)
+		return
+	}
+	if errors.Is(err, ErrNoPizzas) {
+		http.Error(w, "Order must contain at least one pizza", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		slog.Warn("cook request rejected", "orderId", req.OrderID, "error", err)
+		w.Header().Set("Retry-After", strconv.Itoa(int(k.retryAfter.Seconds())))
+		http.Error(w, "Kitchen is at capacity, try again later", http.StatusServiceUnavailable)
+		return
+	}
 
 	// Return accepted response immediately
 	resp := CookResponse{
-		OrderID: req.OrderID,
-		Status:  "cooking",
-		Message: fmt.Sprintf("Started cooking %d item(s)", len(req.OrderItems)),
+		OrderID:       req.OrderID,
+		Status:        "cooking",
+		QueuePosition: position,
+		Message:       fmt.Sprintf("Started cooking %d item(s)", len(req.OrderItems)),
+	}
+	if position > 0 {
+		resp.Status = "queued"
+		resp.Message = fmt.Sprintf("Queued %d item(s) at position %d", len(req.OrderItems), position)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -94,44 +251,152 @@ func (k *Kitchen) HandleCook(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(resp)
 }
 
-// cookItems simulates cooking each order item with a random cooking time between 1-10 seconds.
-// It logs the cooking progress and sends update events to the store service.
-func (k *Kitchen) cookItems(ctx context.Context, orderID uuid.UUID, items []OrderItem) {
-	for _, item := range items {
-		for i := 0; i < item.Quantity; i++ {
-			// Get cooking time
-			cookingTime := k.cookingTimeFunc()
-			startTime := time.Now()
-
-			// Send update events every second while cooking
-			for elapsed := 0; elapsed < cookingTime; elapsed++ {
-				select {
-				case <-ctx.Done():
-					slog.Warn("cooking cancelled", "orderId", orderID, "error", ctx.Err())
-					return
-				default:
-				}
-				k.sendEvent(ctx, orderID, fmt.Sprintf("cooking %s (%d/%d)", item.PizzaType, i+1, item.Quantity))
-				time.Sleep(1 * time.Second)
+// HandleUpdateCook handles PUT /cook/{orderId} requests to change the items of
+// an order still waiting in the kitchen queue. The order keeps its place in the
+// queue. Returns 404 if the order is not in the kitchen and 409 Conflict once a
+// cook station has started on it.
+func (k *Kitchen) HandleUpdateCook(w http.ResponseWriter, r *http.Request) {
+	orderID, err := uuid.Parse(chi.URLParam(r, "orderId"))
+	if err != nil {
+		http.Error(w, "Invalid orderId format", http.StatusBadRequest)
+		return
+	}
+
+	var req CookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	req.OrderID = orderID
+
+	if len(req.OrderItems) == 0 {
+		http.Error(w, "Order must contain at least one item", http.StatusBadRequest)
+		return
+	}
+	if !validQuantities(req.OrderItems) {
+		http.Error(w, "Item quantity must be positive", http.StatusBadRequest)
+		return
+	}
+	if _, ok := priorityLevels[req.Priority]; req.Priority != "" && !ok {
+		http.Error(w, "Invalid priority", http.StatusBadRequest)
+		return
+	}
+
+	slog.Info("cook update received", "orderId", orderID, "items", len(req.OrderItems))
+
+	position, err := k.replaceJob(k.newCookJob(req))
+	switch {
+	case errors.Is(err, ErrJobNotFound):
+		http.Error(w, "Order not found", http.StatusNotFound)
+		return
+	case errors.Is(err, ErrJobStarted):
+		slog.Warn("cook update rejected", "orderId", orderID, "error", err)
+		http.Error(w, "Order has already started cooking", http.StatusConflict)
+		return
+	case errors.Is(err, ErrNoPizzas):
+		http.Error(w, "Order must contain at least one pizza", http.StatusBadRequest)
+		return
+	}
+
+	resp := CookResponse{
+		OrderID:       orderID,
+		Status:        JobQueued,
+		QueuePosition: position,
+		Message:       fmt.Sprintf("Updated to %d item(s) at position %d", len(req.OrderItems), position),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		slog.Error("failed to encode cook response", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleGetJobs handles GET /cook requests.
+// Returns the queued and cooking orders, oldest first.
+func (k *Kitchen) HandleGetJobs(w http.ResponseWriter, r *http.Request) {
+	jobs := k.ActiveJobs()
+	slog.Info("getting active cook jobs", "count", len(jobs))
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(jobs); err != nil {
+		slog.Error("failed to encode cook jobs", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleGetJob handles GET /cook/{orderId} requests.
+// Returns the status of an order in the kitchen with per-pizza progress,
+// or 404 if the kitchen has never seen the order.
+func (k *Kitchen) HandleGetJob(w http.ResponseWriter, r *http.Request) {
+	orderID, err := uuid.Parse(chi.URLParam(r, "orderId"))
+	if err != nil {
+		http.Error(w, "Invalid orderId format", http.StatusBadRequest)
+		return
+	}
+
+	job, ok := k.GetJob(orderID)
+	if !ok {
+		slog.Warn("cook job not found", "orderId", orderID)
+		http.Error(w, "Order not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(job); err != nil {
+		slog.Error("failed to encode cook job", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// cookTask simulates cooking a single pizza through its prep, baking and
+// resting stages, advancing the order's progress every second. When the last
+// pizza of the order is done a DONE event is sent; if the pizza burns the
+// whole order fails with a FAILED event.
+func (k *Kitchen) cookTask(station int, task *cookTask) {
+	job := task.job
+	if k.jobFailed(job) {
+		return
+	}
+
+	startTime := time.Now()
+	for _, stage := range task.stages {
+		k.startStage(task, stage.name)
+		k.reportProgress(job, stage.name)
+
+		for elapsed := 0; elapsed < stage.seconds; elapsed++ {
+			select {
+			case <-job.ctx.Done():
+				slog.Warn("cooking cancelled", "orderId", job.orderID, "station", station, "error", job.ctx.Err())
+				return
+			case <-time.After(1 * time.Second):
 			}
+			k.advanceTask(task)
+			k.reportProgress(job, stage.name)
+		}
 
-			duration := time.Since(startTime)
-			slog.Info("item cooked", "orderId", orderID, "pizzaType", item.PizzaType, "duration", duration.Round(time.Second))
+		if stage.name == StageBaking && k.burnRate > 0 && k.randFloat64() < k.burnRate {
+			k.failJob(task, fmt.Sprintf("%s burnt in the oven", task.pizzaType))
+			return
 		}
 	}
-	slog.Info("all items cooked", "orderId", orderID)
 
-	// Send DONE event
-	k.sendEvent(ctx, orderID, "DONE")
+	duration := time.Since(startTime)
+	slog.Info("item cooked", "orderId", job.orderID, "pizzaType", task.pizzaType, "station", station, "duration", duration.Round(time.Second))
+
+	if k.finishTask(task) {
+		slog.Info("all items cooked", "orderId", job.orderID)
+		k.sendEvent(k.ctx, OrderEvent{OrderID: job.orderID, Status: "DONE", Progress: 100})
+	}
 }
 
 // sendEvent sends an event to the store service.
-func (k *Kitchen) sendEvent(ctx context.Context, orderID uuid.UUID, status string) {
-	event := OrderEvent{
-		OrderID: orderID,
-		Status:  status,
-		Source:  "kitchen",
-	}
+func (k *Kitchen) sendEvent(ctx context.Context, event OrderEvent) {
+	event.Source = "kitchen"
+	orderID := event.OrderID
 
 	body, err := json.Marshal(event)
 	if err != nil {
@@ -148,7 +413,7 @@ func (k *Kitchen) sendEvent(ctx context.Context, orderID uuid.UUID, status strin
 
 	resp, err := k.httpClient.Do(req)
 	if err != nil {
-		slog.Error("failed to send event to store", "orderId", orderID, "status", status, "error", err)
+		slog.Error("failed to send event to store", "orderId", orderID, "status", event.Status, "error", err)
 		return
 	}
 	defer resp.Body.Close()
@@ -5,6 +5,8 @@ package kitchen
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -81,6 +83,35 @@ func TestCookEndpointEmptyOrderItems(t *testing.T) {
 	}
 }
 
+// TestCookEndpointRejectsOrdersWithoutPizzas tests that the /cook endpoint returns bad request for
+// items without a positive quantity, and that jobs without pizzas never reach the stations.
+func TestCookEndpointRejectsOrdersWithoutPizzas(t *testing.T) {
+	kitchen := NewKitchen()
+	defer kitchen.Stop()
+	router := chi.NewRouter()
+	router.Post("/cook", kitchen.HandleCook)
+
+	for _, quantity := range []int{0, -1} {
+		body, _ := json.Marshal(CookRequest{
+			OrderID:    uuid.New(),
+			OrderItems: []OrderItem{{PizzaType: "x", Quantity: quantity}},
+		})
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/cook", bytes.NewReader(body)))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status %d for quantity %d, got %d", http.StatusBadRequest, quantity, rr.Code)
+		}
+	}
+
+	job := kitchen.newCookJob(CookRequest{OrderID: uuid.New()})
+	if _, err := kitchen.enqueue(job); !errors.Is(err, ErrNoPizzas) {
+		t.Errorf("expected a job without pizzas to be refused, got %v", err)
+	}
+	if _, err := kitchen.replaceJob(job); !errors.Is(err, ErrNoPizzas) {
+		t.Errorf("expected a job without pizzas not to replace another, got %v", err)
+	}
+}
+
 // TestCookEndpointReturnsResponse tests that the /cook endpoint returns a proper response body.
 func TestCookEndpointReturnsResponse(t *testing.T) {
 	kitchen := NewKitchen()
@@ -190,3 +221,677 @@ func TestCookSendsUpdateEventsToStore(t *testing.T) {
 		}
 	}
 }
+
+// postCook sends a cook request for a single Margherita to the router and returns the recorder.
+func postCook(router http.Handler, orderID uuid.UUID) *httptest.ResponseRecorder {
+	req := CookRequest{
+		OrderID: orderID,
+		OrderItems: []OrderItem{
+			{PizzaType: "Margherita", Quantity: 1},
+		},
+	}
+	body, _ := json.Marshal(req)
+
+	httpReq := httptest.NewRequest(http.MethodPost, "/cook", bytes.NewReader(body))
+	httpReq.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httpReq)
+	return rr
+}
+
+// TestCookEndpointReturnsQueuePosition tests that orders waiting for a station report their queue position.
+func TestCookEndpointReturnsQueuePosition(t *testing.T) {
+	storeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer storeServer.Close()
+
+	kitchen := NewKitchenWithConfig(KitchenConfig{
+		StoreURL:        storeServer.URL,
+		CookingTimeFunc: func() int { return 10 },
+		Stations:        1,
+		QueueSize:       5,
+	})
+	defer kitchen.Stop()
+
+	router := chi.NewRouter()
+	router.Post("/cook", kitchen.HandleCook)
+
+	expected := []struct {
+		status   string
+		position int
+	}{
+		{"cooking", 0},
+		{"queued", 1},
+		{"queued", 2},
+	}
+
+	for i, want := range expected {
+		rr := postCook(router, uuid.New())
+		if rr.Code != http.StatusAccepted {
+			t.Fatalf("request %d: expected status %d, got %d", i, http.StatusAccepted, rr.Code)
+		}
+
+		var resp CookResponse
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatalf("request %d: failed to decode response: %v", i, err)
+		}
+		if resp.Status != want.status {
+			t.Errorf("request %d: expected status '%s', got '%s'", i, want.status, resp.Status)
+		}
+		if resp.QueuePosition != want.position {
+			t.Errorf("request %d: expected queue position %d, got %d", i, want.position, resp.QueuePosition)
+		}
+	}
+}
+
+// TestCookEndpointQueueFull tests that the /cook endpoint returns 503 with Retry-After when the queue is full.
+func TestCookEndpointQueueFull(t *testing.T) {
+	storeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer storeServer.Close()
+
+	kitchen := NewKitchenWithConfig(KitchenConfig{
+		StoreURL:        storeServer.URL,
+		CookingTimeFunc: func() int { return 10 },
+		Stations:        1,
+		QueueSize:       1,
+		RetryAfter:      30 * time.Second,
+	})
+	defer kitchen.Stop()
+
+	router := chi.NewRouter()
+	router.Post("/cook", kitchen.HandleCook)
+
+	// One order on the station, one waiting in the queue
+	for i := 0; i < 2; i++ {
+		if rr := postCook(router, uuid.New()); rr.Code != http.StatusAccepted {
+			t.Fatalf("request %d: expected status %d, got %d", i, http.StatusAccepted, rr.Code)
+		}
+	}
+
+	rr := postCook(router, uuid.New())
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status %d, got %d", http.StatusServiceUnavailable, rr.Code)
+	}
+	if got := rr.Header().Get("Retry-After"); got != "30" {
+		t.Errorf("expected Retry-After '30', got '%s'", got)
+	}
+}
+
+// TestCookStationsProcessQueueInOrder tests that queued orders are cooked in FIFO order.
+func TestCookStationsProcessQueueInOrder(t *testing.T) {
+	doneOrder := make(chan uuid.UUID, 10)
+	storeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event OrderEvent
+		json.NewDecoder(r.Body).Decode(&event)
+		if event.Status == "DONE" {
+			doneOrder <- event.OrderID
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer storeServer.Close()
+
+	kitchen := NewKitchenWithConfig(KitchenConfig{
+		StoreURL:        storeServer.URL,
+		CookingTimeFunc: func() int { return 1 },
+		Stations:        1,
+	})
+	defer kitchen.Stop()
+
+	router := chi.NewRouter()
+	router.Post("/cook", kitchen.HandleCook)
+
+	orderIDs := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+	for _, id := range orderIDs {
+		postCook(router, id)
+	}
+
+	for i, want := range orderIDs {
+		select {
+		case got := <-doneOrder:
+			if got != want {
+				t.Errorf("order %d: expected %s to finish, got %s", i, want, got)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for order %d", i)
+		}
+	}
+}
+
+// TestCookPizzasOfOneOrderInParallel tests that pizzas of the same order are cooked on several stations at once.
+func TestCookPizzasOfOneOrderInParallel(t *testing.T) {
+	eventsReceived := make(chan OrderEvent, 100)
+	storeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event OrderEvent
+		json.NewDecoder(r.Body).Decode(&event)
+		eventsReceived <- event
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer storeServer.Close()
+
+	kitchen := NewKitchenWithConfig(KitchenConfig{
+		StoreURL:        storeServer.URL,
+		CookingTimeFunc: func() int { return 2 },
+		Stations:        4,
+	})
+	defer kitchen.Stop()
+
+	router := chi.NewRouter()
+	router.Post("/cook", kitchen.HandleCook)
+
+	orderID := uuid.New()
+	req := CookRequest{
+		OrderID: orderID,
+		OrderItems: []OrderItem{
+			{PizzaType: "Margherita", Quantity: 3},
+			{PizzaType: "Pepperoni", Quantity: 1},
+		},
+	}
+	body, _ := json.Marshal(req)
+	httpReq := httptest.NewRequest(http.MethodPost, "/cook", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+
+	start := time.Now()
+	router.ServeHTTP(rr, httpReq)
+
+	// Serial cooking would take 8 seconds; in parallel it takes 2
+	var progress []string
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case event := <-eventsReceived:
+			if event.Status != "DONE" {
+				progress = append(progress, event.Status)
+				continue
+			}
+			if elapsed := time.Since(start); elapsed > 4*time.Second {
+				t.Errorf("expected pizzas to cook in parallel, took %s", elapsed)
+			}
+			if len(progress) == 0 || progress[0] != "baking 0%" {
+				t.Errorf("expected first progress event 'baking 0%%', got %v", progress)
+			}
+			for _, status := range progress {
+				var percent int
+				if _, err := fmt.Sscanf(status, "baking %d%%", &percent); err != nil {
+					t.Errorf("expected percentage progress event, got '%s'", status)
+				}
+			}
+			return
+		case <-timeout:
+			t.Fatal("timed out waiting for DONE event")
+		}
+	}
+}
+
+// TestCookSendsFailedEventWhenPizzaBurns tests that a failed pizza fails the whole order with a FAILED event.
+func TestCookSendsFailedEventWhenPizzaBurns(t *testing.T) {
+	eventsReceived := make(chan OrderEvent, 100)
+	storeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event OrderEvent
+		json.NewDecoder(r.Body).Decode(&event)
+		eventsReceived <- event
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer storeServer.Close()
+
+	kitchen := NewKitchenWithConfig(KitchenConfig{
+		StoreURL:        storeServer.URL,
+		CookingTimeFunc: func() int { return 1 },
+		BurnRate:        1,
+	})
+	defer kitchen.Stop()
+
+	router := chi.NewRouter()
+	router.Post("/cook", kitchen.HandleCook)
+
+	orderID := uuid.New()
+	req := CookRequest{
+		OrderID: orderID,
+		OrderItems: []OrderItem{
+			{PizzaType: "Margherita", Quantity: 2},
+		},
+	}
+	body, _ := json.Marshal(req)
+	httpReq := httptest.NewRequest(http.MethodPost, "/cook", bytes.NewReader(body))
+	router.ServeHTTP(httptest.NewRecorder(), httpReq)
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case event := <-eventsReceived:
+			switch event.Status {
+			case "DONE":
+				t.Fatal("expected no DONE event for a failed order")
+			case "FAILED":
+				if event.Reason == "" {
+					t.Error("expected FAILED event to carry a reason")
+				}
+				// No DONE should follow the failure
+				select {
+				case late := <-eventsReceived:
+					if late.Status == "DONE" || late.Status == "FAILED" {
+						t.Errorf("expected no further terminal events, got '%s'", late.Status)
+					}
+				case <-time.After(1500 * time.Millisecond):
+				}
+				return
+			}
+		case <-timeout:
+			t.Fatal("timed out waiting for FAILED event")
+		}
+	}
+}
+
+// TestGetCookJobReturnsItemProgress tests that GET /cook/{orderId} returns the job with per-pizza status.
+func TestGetCookJobReturnsItemProgress(t *testing.T) {
+	storeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer storeServer.Close()
+
+	kitchen := NewKitchenWithConfig(KitchenConfig{
+		StoreURL:        storeServer.URL,
+		CookingTimeFunc: func() int { return 10 },
+		Stations:        1,
+	})
+	defer kitchen.Stop()
+
+	router := chi.NewRouter()
+	router.Post("/cook", kitchen.HandleCook)
+	router.Get("/cook/{orderId}", kitchen.HandleGetJob)
+
+	orderID := uuid.New()
+	req := CookRequest{
+		OrderID: orderID,
+		OrderItems: []OrderItem{
+			{PizzaType: "Margherita", Quantity: 2},
+		},
+	}
+	body, _ := json.Marshal(req)
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/cook", bytes.NewReader(body)))
+
+	// Give the station a moment to pick up the first pizza
+	time.Sleep(100 * time.Millisecond)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/cook/"+orderID.String(), nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	var job CookJobStatus
+	if err := json.NewDecoder(rr.Body).Decode(&job); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if job.OrderID != orderID {
+		t.Errorf("expected orderID %s, got %s", orderID, job.OrderID)
+	}
+	if job.Status != JobCooking {
+		t.Errorf("expected status '%s', got '%s'", JobCooking, job.Status)
+	}
+	if job.StartedAt == nil {
+		t.Error("expected startedAt to be set")
+	}
+	if len(job.Items) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(job.Items))
+	}
+	if job.Items[0].Status != ItemCooking || job.Items[0].Station != "station-1" {
+		t.Errorf("expected first pizza cooking on station-1, got '%s' on '%s'", job.Items[0].Status, job.Items[0].Station)
+	}
+	if job.Items[1].Status != ItemWaiting || job.Items[1].Station != "" {
+		t.Errorf("expected second pizza waiting without a station, got '%s' on '%s'", job.Items[1].Status, job.Items[1].Station)
+	}
+}
+
+// TestGetCookJobNotFound tests that GET /cook/{orderId} returns 404 for unknown orders.
+func TestGetCookJobNotFound(t *testing.T) {
+	kitchen := NewKitchen()
+	defer kitchen.Stop()
+
+	router := chi.NewRouter()
+	router.Get("/cook/{orderId}", kitchen.HandleGetJob)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/cook/"+uuid.New().String(), nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d", http.StatusNotFound, rr.Code)
+	}
+}
+
+// TestGetCookJobsListsActiveJobs tests that GET /cook lists queued and cooking jobs but not finished ones.
+func TestGetCookJobsListsActiveJobs(t *testing.T) {
+	done := make(chan struct{}, 1)
+	storeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event OrderEvent
+		json.NewDecoder(r.Body).Decode(&event)
+		if event.Status == "DONE" {
+			done <- struct{}{}
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer storeServer.Close()
+
+	cookingTimes := make(chan int, 3)
+	cookingTimes <- 1
+	cookingTimes <- 10
+	cookingTimes <- 10
+	kitchen := NewKitchenWithConfig(KitchenConfig{
+		StoreURL:        storeServer.URL,
+		CookingTimeFunc: func() int { return <-cookingTimes },
+		Stations:        2,
+	})
+	defer kitchen.Stop()
+
+	router := chi.NewRouter()
+	router.Post("/cook", kitchen.HandleCook)
+	router.Get("/cook", kitchen.HandleGetJobs)
+
+	finished, cooking, queued := uuid.New(), uuid.New(), uuid.New()
+	for _, id := range []uuid.UUID{finished, cooking, queued} {
+		postCook(router, id)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out waiting for first order to finish")
+	}
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/cook", nil))
+
+	var jobs []CookJobStatus
+	if err := json.NewDecoder(rr.Body).Decode(&jobs); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(jobs) != 2 {
+		t.Fatalf("expected 2 active jobs, got %d", len(jobs))
+	}
+	if jobs[0].OrderID != cooking || jobs[1].OrderID != queued {
+		t.Errorf("expected active jobs [%s %s], got [%s %s]", cooking, queued, jobs[0].OrderID, jobs[1].OrderID)
+	}
+}
+
+// TestCookEndpointRejectsDuplicateOrder tests that an order already in the kitchen cannot be queued twice.
+func TestCookEndpointRejectsDuplicateOrder(t *testing.T) {
+	storeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer storeServer.Close()
+
+	kitchen := NewKitchenWithConfig(KitchenConfig{
+		StoreURL:        storeServer.URL,
+		CookingTimeFunc: func() int { return 10 },
+	})
+	defer kitchen.Stop()
+
+	router := chi.NewRouter()
+	router.Post("/cook", kitchen.HandleCook)
+
+	orderID := uuid.New()
+	postCook(router, orderID)
+	if rr := postCook(router, orderID); rr.Code != http.StatusConflict {
+		t.Errorf("expected status %d, got %d", http.StatusConflict, rr.Code)
+	}
+}
+
+// TestCookQueueServesHigherPriorityFirst tests that express and VIP orders overtake normal orders in the queue.
+func TestCookQueueServesHigherPriorityFirst(t *testing.T) {
+	doneOrder := make(chan uuid.UUID, 10)
+	storeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event OrderEvent
+		json.NewDecoder(r.Body).Decode(&event)
+		if event.Status == "DONE" {
+			doneOrder <- event.OrderID
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer storeServer.Close()
+
+	kitchen := NewKitchenWithConfig(KitchenConfig{
+		StoreURL:        storeServer.URL,
+		CookingTimeFunc: func() int { return 1 },
+		Stations:        1,
+	})
+	defer kitchen.Stop()
+
+	router := chi.NewRouter()
+	router.Post("/cook", kitchen.HandleCook)
+
+	first, normal, express, vip := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	requests := []struct {
+		orderID  uuid.UUID
+		priority string
+		position int
+	}{
+		{first, "", 0},
+		{normal, PriorityNormal, 1},
+		{express, PriorityExpress, 1},
+		{vip, PriorityVIP, 1},
+	}
+	for _, r := range requests {
+		req := CookRequest{
+			OrderID:    r.orderID,
+			OrderItems: []OrderItem{{PizzaType: "Margherita", Quantity: 1}},
+			Priority:   r.priority,
+		}
+		body, _ := json.Marshal(req)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/cook", bytes.NewReader(body)))
+
+		var resp CookResponse
+		json.NewDecoder(rr.Body).Decode(&resp)
+		if resp.QueuePosition != r.position {
+			t.Errorf("order with priority '%s': expected queue position %d, got %d", r.priority, r.position, resp.QueuePosition)
+		}
+
+		// Let the station pick up the first order before queueing the others
+		if r.orderID == first {
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+
+	for i, want := range []uuid.UUID{first, vip, express, normal} {
+		select {
+		case got := <-doneOrder:
+			if got != want {
+				t.Errorf("order %d: expected %s to finish, got %s", i, want, got)
+			}
+		case <-time.After(6 * time.Second):
+			t.Fatalf("timed out waiting for order %d", i)
+		}
+	}
+}
+
+// TestCookEndpointInvalidPriority tests that the /cook endpoint rejects unknown priorities.
+func TestCookEndpointInvalidPriority(t *testing.T) {
+	kitchen := NewKitchen()
+	defer kitchen.Stop()
+
+	router := chi.NewRouter()
+	router.Post("/cook", kitchen.HandleCook)
+
+	req := CookRequest{
+		OrderID:    uuid.New(),
+		OrderItems: []OrderItem{{PizzaType: "Margherita", Quantity: 1}},
+		Priority:   "urgent",
+	}
+	body, _ := json.Marshal(req)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/cook", bytes.NewReader(body)))
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, rr.Code)
+	}
+}
+
+// TestCookQueueAgingPreventsStarvation tests that long-waiting normal orders outrank newly queued VIP orders.
+func TestCookQueueAgingPreventsStarvation(t *testing.T) {
+	kitchen := NewKitchenWithConfig(KitchenConfig{AgingInterval: 30 * time.Second})
+	defer kitchen.Stop()
+
+	now := time.Now()
+	old := &cookJob{priority: PriorityNormal, queuedAt: now.Add(-90 * time.Second)}
+	vip := &cookJob{priority: PriorityVIP, queuedAt: now}
+
+	if kitchen.rank(old, now) <= kitchen.rank(vip, now) {
+		t.Errorf("expected normal order waiting 90s to outrank a new VIP order, got %.2f <= %.2f",
+			kitchen.rank(old, now), kitchen.rank(vip, now))
+	}
+}
+
+// TestCookReportsStagesInProgressEvents tests that progress events report the prep, baking and resting stages.
+func TestCookReportsStagesInProgressEvents(t *testing.T) {
+	eventsReceived := make(chan OrderEvent, 100)
+	storeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event OrderEvent
+		json.NewDecoder(r.Body).Decode(&event)
+		eventsReceived <- event
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer storeServer.Close()
+
+	kitchen := NewKitchenWithConfig(KitchenConfig{
+		StoreURL: storeServer.URL,
+		Profiles: map[string]CookingProfile{
+			"Margherita": {PizzaType: "Margherita", PrepTime: 1, BakeTime: 1, OvenTemperature: 450, RestTime: 1},
+		},
+	})
+	defer kitchen.Stop()
+
+	router := chi.NewRouter()
+	router.Post("/cook", kitchen.HandleCook)
+	postCook(router, uuid.New())
+
+	var stages []string
+	timeout := time.After(6 * time.Second)
+	for {
+		select {
+		case event := <-eventsReceived:
+			if event.Status == "DONE" {
+				want := []string{StagePrep, StageBaking, StageResting}
+				if len(stages) != len(want) {
+					t.Fatalf("expected stages %v, got %v", want, stages)
+				}
+				for i := range want {
+					if stages[i] != want[i] {
+						t.Errorf("expected stages %v, got %v", want, stages)
+						break
+					}
+				}
+				return
+			}
+			if len(stages) == 0 || stages[len(stages)-1] != event.Stage {
+				stages = append(stages, event.Stage)
+			}
+			if event.Status != fmt.Sprintf("%s %d%%", event.Stage, event.Progress) {
+				t.Errorf("expected status to report stage and progress, got '%s'", event.Status)
+			}
+		case <-timeout:
+			t.Fatal("timed out waiting for DONE event")
+		}
+	}
+}
+
+// TestUpdateCookReplacesQueuedItems tests that PUT /cook/{orderId} changes the items of a queued order,
+// keeping its place in the queue, and is rejected once a station has started on the order.
+func TestUpdateCookReplacesQueuedItems(t *testing.T) {
+	storeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer storeServer.Close()
+
+	kitchen := NewKitchenWithConfig(KitchenConfig{
+		StoreURL:        storeServer.URL,
+		CookingTimeFunc: func() int { return 10 },
+		Stations:        1,
+	})
+	defer kitchen.Stop()
+
+	router := chi.NewRouter()
+	router.Post("/cook", kitchen.HandleCook)
+	router.Put("/cook/{orderId}", kitchen.HandleUpdateCook)
+
+	cooking, queued := uuid.New(), uuid.New()
+	postCook(router, cooking)
+	// Let the station pick up the first order before queueing the second
+	time.Sleep(100 * time.Millisecond)
+	postCook(router, queued)
+
+	putCook := func(orderID uuid.UUID, items []OrderItem) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(CookRequest{OrderItems: items})
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodPut, "/cook/"+orderID.String(), bytes.NewReader(body)))
+		return rr
+	}
+
+	rr := putCook(queued, []OrderItem{{PizzaType: "Pepperoni", Quantity: 2}})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	var resp CookResponse
+	json.NewDecoder(rr.Body).Decode(&resp)
+	if resp.QueuePosition != 1 {
+		t.Errorf("expected the order to keep queue position 1, got %d", resp.QueuePosition)
+	}
+
+	job, _ := kitchen.GetJob(queued)
+	if len(job.Items) != 2 || job.Items[0].PizzaType != "Pepperoni" || job.Priority != PriorityNormal {
+		t.Errorf("expected 2 normal priority Pepperoni pizzas, got %+v", job)
+	}
+
+	if rr := putCook(cooking, []OrderItem{{PizzaType: "Pepperoni", Quantity: 1}}); rr.Code != http.StatusConflict {
+		t.Errorf("expected status %d for an order being cooked, got %d", http.StatusConflict, rr.Code)
+	}
+	if rr := putCook(uuid.New(), []OrderItem{{PizzaType: "Pepperoni", Quantity: 1}}); rr.Code != http.StatusNotFound {
+		t.Errorf("expected status %d for an unknown order, got %d", http.StatusNotFound, rr.Code)
+	}
+	if rr := putCook(queued, nil); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d for an empty order, got %d", http.StatusBadRequest, rr.Code)
+	}
+	if rr := putCook(queued, []OrderItem{{PizzaType: "Pepperoni", Quantity: 0}}); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d for an order without pizzas, got %d", http.StatusBadRequest, rr.Code)
+	}
+}
+
+// TestFinishedJobsAreForgotten tests that cooked orders can be looked up until the job retention
+// period has passed, and are then removed from the kitchen.
+func TestFinishedJobsAreForgotten(t *testing.T) {
+	storeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer storeServer.Close()
+
+	kitchen := NewKitchenWithConfig(KitchenConfig{
+		StoreURL:        storeServer.URL,
+		CookingTimeFunc: func() int { return 1 },
+		JobRetention:    300 * time.Millisecond,
+	})
+	defer kitchen.Stop()
+
+	router := chi.NewRouter()
+	router.Post("/cook", kitchen.HandleCook)
+	orderID := uuid.New()
+	postCook(router, orderID)
+
+	deadline := time.Now().Add(5 * time.Second)
+	done := false
+	for {
+		job, ok := kitchen.GetJob(orderID)
+		if ok && job.Status == JobDone {
+			done = true
+		}
+		if !ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the finished order to be forgotten, got status '%s'", job.Status)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if !done {
+		t.Error("expected the cooked order to be kept for a while after it finished")
+	}
+}
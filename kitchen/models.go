@@ -2,7 +2,11 @@
 // It handles cooking pizza orders by processing order items with simulated cooking times.
 package kitchen
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 // OrderItem represents a single item in an order, containing the pizza type
 // and the quantity requested.
@@ -11,18 +15,36 @@ type OrderItem struct {
 	Quantity  int    `json:"quantity"`
 }
 
+// Order priority constants, from lowest to highest.
+const (
+	PriorityNormal  = "normal"
+	PriorityExpress = "express"
+	PriorityVIP     = "vip"
+)
+
+// priorityLevels maps each priority to its rank in the kitchen queue.
+var priorityLevels = map[string]int{
+	PriorityNormal:  0,
+	PriorityExpress: 1,
+	PriorityVIP:     2,
+}
+
 // CookRequest represents the request body for cooking an order.
-// It contains the order ID and the items to be cooked.
+// It contains the order ID, the items to be cooked and the order priority.
 type CookRequest struct {
 	OrderID    uuid.UUID   `json:"orderId"`
 	OrderItems []OrderItem `json:"orderItems"`
+	Priority   string      `json:"priority,omitempty"`
 }
 
 // CookResponse represents the response returned after accepting a cook request.
+// QueuePosition is the order's position in the kitchen queue, or 0 when a
+// cook station started on it immediately.
 type CookResponse struct {
-	OrderID uuid.UUID `json:"orderId"`
-	Status  string    `json:"status"`
-	Message string    `json:"message,omitempty"`
+	OrderID       uuid.UUID `json:"orderId"`
+	Status        string    `json:"status"`
+	QueuePosition int       `json:"queuePosition"`
+	Message       string    `json:"message,omitempty"`
 }
 
 // CookedItem represents a single item that has been cooked, including the time
@@ -32,3 +54,46 @@ type CookedItem struct {
 	Quantity    int    `json:"quantity"`
 	CookingTime int    `json:"cookingTime"` // in seconds
 }
+
+// Cook job status constants.
+const (
+	JobQueued  = "queued"
+	JobCooking = "cooking"
+	JobDone    = "done"
+	JobFailed  = "failed"
+)
+
+// Cook item status constants.
+const (
+	ItemWaiting   = "waiting"
+	ItemCooking   = "cooking"
+	ItemCooked    = "cooked"
+	ItemFailed    = "failed"
+	ItemCancelled = "cancelled"
+)
+
+// CookJobStatus represents the state of an order tracked by the kitchen,
+// as returned by GET /cook and GET /cook/{orderId}.
+type CookJobStatus struct {
+	OrderID    uuid.UUID        `json:"orderId"`
+	Status     string           `json:"status"`
+	Priority   string           `json:"priority"`
+	Progress   int              `json:"progress"` // percentage of the order's total cooking time
+	Items      []CookItemStatus `json:"items"`
+	QueuedAt   time.Time        `json:"queuedAt"`
+	StartedAt  *time.Time       `json:"startedAt,omitempty"`
+	FinishedAt *time.Time       `json:"finishedAt,omitempty"`
+}
+
+// CookItemStatus represents the state of a single pizza of an order.
+type CookItemStatus struct {
+	PizzaType       string     `json:"pizzaType"`
+	Status          string     `json:"status"`
+	Stage           string     `json:"stage,omitempty"`
+	Station         string     `json:"station,omitempty"`
+	OvenTemperature int        `json:"ovenTemperature,omitempty"` // in degrees Celsius
+	Progress        int        `json:"progress"`
+	CookingTime     int        `json:"cookingTime"` // in seconds, across all stages
+	StartedAt       *time.Time `json:"startedAt,omitempty"`
+	FinishedAt      *time.Time `json:"finishedAt,omitempty"`
+}
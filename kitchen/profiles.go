@@ -0,0 +1,126 @@
+package kitchen
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+)
+
+// Cooking stage constants, in the order a pizza goes through them.
+const (
+	StagePrep    = "prep"
+	StageBaking  = "baking"
+	StageResting = "resting"
+)
+
+// Bake time distributions for cooking profiles.
+const (
+	DistributionUniform = "uniform"
+	DistributionNormal  = "normal"
+)
+
+// CookingProfile describes how a pizza type is cooked. Prep and rest times are
+// fixed; the bake time varies around BakeTime following Distribution, with
+// BakeVariance being the maximum deviation (uniform) or the standard deviation
+// (normal). All times are in seconds.
+type CookingProfile struct {
+	PizzaType       string `json:"pizzaType"`
+	PrepTime        int    `json:"prepTime"`
+	BakeTime        int    `json:"bakeTime"`
+	BakeVariance    int    `json:"bakeVariance"`
+	Distribution    string `json:"distribution"`
+	OvenTemperature int    `json:"ovenTemperature"` // in degrees Celsius
+	RestTime        int    `json:"restTime"`
+}
+
+// cookingStage is one stage of a pizza's cooking plan.
+type cookingStage struct {
+	name    string
+	seconds int
+}
+
+// DefaultCookingProfiles returns the cooking profiles for the pizzas on the menu.
+func DefaultCookingProfiles() map[string]CookingProfile {
+	profiles := []CookingProfile{
+		{PizzaType: "Margherita", PrepTime: 2, BakeTime: 5, BakeVariance: 2, Distribution: DistributionUniform, OvenTemperature: 450, RestTime: 1},
+		{PizzaType: "Pepperoni", PrepTime: 2, BakeTime: 6, BakeVariance: 2, Distribution: DistributionUniform, OvenTemperature: 450, RestTime: 1},
+		{PizzaType: "Hawaiian", PrepTime: 3, BakeTime: 7, BakeVariance: 2, Distribution: DistributionNormal, OvenTemperature: 430, RestTime: 1},
+		{PizzaType: "Vegan", PrepTime: 3, BakeTime: 6, BakeVariance: 1, Distribution: DistributionNormal, OvenTemperature: 420, RestTime: 1},
+		{PizzaType: "Veggie", PrepTime: 3, BakeTime: 6, BakeVariance: 1, Distribution: DistributionNormal, OvenTemperature: 420, RestTime: 1},
+	}
+
+	byType := make(map[string]CookingProfile, len(profiles))
+	for _, p := range profiles {
+		byType[p.PizzaType] = p
+	}
+	return byType
+}
+
+// LoadCookingProfiles reads cooking profiles from a JSON file containing an
+// array of profiles.
+func LoadCookingProfiles(path string) (map[string]CookingProfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading cooking profiles: %w", err)
+	}
+
+	var profiles []CookingProfile
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		return nil, fmt.Errorf("parsing cooking profiles: %w", err)
+	}
+
+	byType := make(map[string]CookingProfile, len(profiles))
+	for _, p := range profiles {
+		if p.PizzaType == "" {
+			return nil, fmt.Errorf("cooking profile without pizzaType")
+		}
+		if p.BakeTime <= 0 || p.PrepTime < 0 || p.RestTime < 0 || p.BakeVariance < 0 {
+			return nil, fmt.Errorf("invalid cooking times for %s", p.PizzaType)
+		}
+		switch p.Distribution {
+		case "":
+			p.Distribution = DistributionUniform
+		case DistributionUniform, DistributionNormal:
+		default:
+			return nil, fmt.Errorf("unknown distribution %q for %s", p.Distribution, p.PizzaType)
+		}
+		byType[p.PizzaType] = p
+	}
+	return byType, nil
+}
+
+// cookingPlan returns the stages for one pizza and the oven temperature it
+// needs. Pizza types without a profile are only baked, for the time returned
+// by the kitchen's cooking time function.
+func (k *Kitchen) cookingPlan(pizzaType string) ([]cookingStage, int) {
+	profile, ok := k.profiles[pizzaType]
+	if !ok {
+		return []cookingStage{{name: StageBaking, seconds: k.cookingTimeFunc()}}, 0
+	}
+
+	var stages []cookingStage
+	if profile.PrepTime > 0 {
+		stages = append(stages, cookingStage{name: StagePrep, seconds: profile.PrepTime})
+	}
+	stages = append(stages, cookingStage{name: StageBaking, seconds: k.bakeTime(profile)})
+	if profile.RestTime > 0 {
+		stages = append(stages, cookingStage{name: StageResting, seconds: profile.RestTime})
+	}
+	return stages, profile.OvenTemperature
+}
+
+// bakeTime draws a bake time from the profile's distribution. Pizzas always
+// bake for at least one second.
+func (k *Kitchen) bakeTime(profile CookingProfile) int {
+	deviation := 0.0
+	if profile.BakeVariance > 0 {
+		switch profile.Distribution {
+		case DistributionNormal:
+			deviation = k.randNormFloat64() * float64(profile.BakeVariance)
+		default:
+			deviation = float64(k.randIntn(2*profile.BakeVariance+1) - profile.BakeVariance)
+		}
+	}
+	return max(1, int(math.Round(float64(profile.BakeTime)+deviation)))
+}
@@ -0,0 +1,82 @@
+package kitchen
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestLoadCookingProfiles tests that cooking profiles are read from a JSON file.
+func TestLoadCookingProfiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "profiles.json")
+	data := `[
+		{"pizzaType": "Margherita", "prepTime": 2, "bakeTime": 5, "bakeVariance": 1, "distribution": "normal", "ovenTemperature": 450, "restTime": 1},
+		{"pizzaType": "Calzone", "bakeTime": 8}
+	]`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatalf("failed to write profiles: %v", err)
+	}
+
+	profiles, err := LoadCookingProfiles(path)
+	if err != nil {
+		t.Fatalf("failed to load profiles: %v", err)
+	}
+	if len(profiles) != 2 {
+		t.Fatalf("expected 2 profiles, got %d", len(profiles))
+	}
+	if p := profiles["Margherita"]; p.OvenTemperature != 450 || p.Distribution != DistributionNormal {
+		t.Errorf("unexpected Margherita profile: %+v", p)
+	}
+	if p := profiles["Calzone"]; p.Distribution != DistributionUniform {
+		t.Errorf("expected Calzone to default to uniform distribution, got '%s'", p.Distribution)
+	}
+}
+
+// TestLoadCookingProfilesRejectsInvalidProfiles tests that malformed profiles are reported as errors.
+func TestLoadCookingProfilesRejectsInvalidProfiles(t *testing.T) {
+	tests := map[string]string{
+		"missing pizza type":   `[{"bakeTime": 5}]`,
+		"missing bake time":    `[{"pizzaType": "Margherita"}]`,
+		"unknown distribution": `[{"pizzaType": "Margherita", "bakeTime": 5, "distribution": "poisson"}]`,
+		"invalid json":         `not json`,
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "profiles.json")
+			os.WriteFile(path, []byte(data), 0o644)
+			if _, err := LoadCookingProfiles(path); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+// TestCookingPlanFollowsProfile tests that pizzas are planned through prep, baking and resting stages.
+func TestCookingPlanFollowsProfile(t *testing.T) {
+	kitchen := NewKitchenWithConfig(KitchenConfig{
+		Profiles: map[string]CookingProfile{
+			"Margherita": {PizzaType: "Margherita", PrepTime: 2, BakeTime: 5, BakeVariance: 2, OvenTemperature: 450, RestTime: 1},
+		},
+		CookingTimeFunc: func() int { return 7 },
+	})
+	defer kitchen.Stop()
+
+	for i := 0; i < 50; i++ {
+		stages, temperature := kitchen.cookingPlan("Margherita")
+		if temperature != 450 {
+			t.Fatalf("expected oven temperature 450, got %d", temperature)
+		}
+		if len(stages) != 3 || stages[0].name != StagePrep || stages[1].name != StageBaking || stages[2].name != StageResting {
+			t.Fatalf("expected prep, baking and resting stages, got %+v", stages)
+		}
+		if stages[1].seconds < 3 || stages[1].seconds > 7 {
+			t.Errorf("expected bake time between 3 and 7 seconds, got %d", stages[1].seconds)
+		}
+	}
+
+	// Pizza types without a profile are only baked
+	stages, _ := kitchen.cookingPlan("Calzone")
+	if len(stages) != 1 || stages[0].name != StageBaking || stages[0].seconds != 7 {
+		t.Errorf("expected a single 7 second baking stage, got %+v", stages)
+	}
+}
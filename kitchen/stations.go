@@ -0,0 +1,465 @@
+package kitchen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Errors returned when a cook request cannot be queued.
+var (
+	ErrQueueFull     = errors.New("kitchen queue is full")
+	ErrKitchenClosed = errors.New("kitchen is closed")
+	ErrJobActive     = errors.New("order is already in the kitchen")
+	ErrNoPizzas      = errors.New("order has no pizzas to cook")
+)
+
+// Errors returned when a queued cook request cannot be changed.
+var (
+	ErrJobNotFound = errors.New("order is not in the kitchen")
+	ErrJobStarted  = errors.New("order has already started cooking")
+)
+
+// cookJob is a cook request waiting in, or taken from, the kitchen queue.
+// The order is split into one task per pizza so that several stations can
+// work on the same order in parallel.
+type cookJob struct {
+	orderID  uuid.UUID
+	items    []OrderItem
+	priority string
+	tasks    []*cookTask
+	ctx      context.Context
+	cancel   context.CancelFunc
+
+	// The fields below are guarded by Kitchen.mu.
+	status       string
+	next         int // index of the next task to hand to a station
+	remaining    int // tasks that have not finished cooking
+	totalSeconds int
+	doneSeconds  int
+	failed       bool
+	queuedAt     time.Time
+	startedAt    *time.Time
+	finishedAt   *time.Time
+
+	// reportMu serialises progress events so the store sees them in order.
+	reportMu    sync.Mutex
+	lastPercent int
+	lastStage   string
+}
+
+// cookTask is a single pizza of an order, cooked by one station.
+type cookTask struct {
+	job             *cookJob
+	pizzaType       string
+	stages          []cookingStage
+	cookingTime     int // total seconds across all stages
+	ovenTemperature int
+
+	// The fields below are guarded by Kitchen.mu.
+	status     string
+	stage      string
+	station    string
+	elapsed    int
+	startedAt  *time.Time
+	finishedAt *time.Time
+}
+
+// newCookJob splits a cook request into per-pizza tasks and plans the cooking
+// stages of each of them from its cooking profile.
+func (k *Kitchen) newCookJob(req CookRequest) *cookJob {
+	ctx, cancel := context.WithCancel(k.ctx)
+	job := &cookJob{
+		orderID:     req.OrderID,
+		items:       req.OrderItems,
+		priority:    req.Priority,
+		ctx:         ctx,
+		cancel:      cancel,
+		status:      JobQueued,
+		queuedAt:    time.Now(),
+		lastPercent: -1,
+	}
+	for _, item := range req.OrderItems {
+		for i := 0; i < item.Quantity; i++ {
+			task := &cookTask{job: job, pizzaType: item.PizzaType, status: ItemWaiting}
+			task.stages, task.ovenTemperature = k.cookingPlan(item.PizzaType)
+			for _, stage := range task.stages {
+				task.cookingTime += stage.seconds
+			}
+			job.tasks = append(job.tasks, task)
+			job.totalSeconds += task.cookingTime
+		}
+	}
+	job.remaining = len(job.tasks)
+	return job
+}
+
+// startStations launches one goroutine per cook station.
+func (k *Kitchen) startStations() {
+	for i := 1; i <= k.stations; i++ {
+		go k.runStation(i)
+	}
+}
+
+// runStation takes pizzas from the queue in FIFO order and cooks them until
+// the kitchen is stopped.
+func (k *Kitchen) runStation(station int) {
+	for {
+		task, ok := k.nextTask(station)
+		if !ok {
+			return
+		}
+
+		slog.Info("station picked up pizza", "station", station, "orderId", task.job.orderID, "pizzaType", task.pizzaType)
+		k.cookTask(station, task)
+
+		k.mu.Lock()
+		k.busy--
+		k.mu.Unlock()
+	}
+}
+
+// nextTask blocks until a pizza is waiting and hands it to the caller, taking
+// it from the highest ranked order. Jobs leave the queue once all of their
+// pizzas have been handed out. It returns false once the kitchen has been
+// stopped.
+func (k *Kitchen) nextTask(station int) (*cookTask, bool) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	for len(k.queue) == 0 && !k.stopped {
+		k.cond.Wait()
+	}
+	if k.stopped {
+		return nil, false
+	}
+
+	k.sortQueue(time.Now())
+	job := k.queue[0]
+	task := job.tasks[job.next]
+	job.next++
+	if job.next == len(job.tasks) {
+		k.queue = k.queue[1:]
+	}
+	k.busy++
+
+	now := time.Now()
+	task.status = ItemCooking
+	task.station = fmt.Sprintf("station-%d", station)
+	task.startedAt = &now
+	if job.startedAt == nil {
+		job.status = JobCooking
+		job.startedAt = &now
+	}
+	return task, true
+}
+
+// enqueue adds a job to the queue. It returns the job's position among the
+// orders waiting for a station, or 0 if a station is free to start on it
+// right away. Jobs without pizzas are refused.
+func (k *Kitchen) enqueue(job *cookJob) (int, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.stopped {
+		return 0, ErrKitchenClosed
+	}
+	if len(job.tasks) == 0 {
+		return 0, ErrNoPizzas
+	}
+	if existing, ok := k.jobs[job.orderID]; ok && (existing.status == JobQueued || existing.status == JobCooking) {
+		return 0, ErrJobActive
+	}
+
+	now := time.Now()
+	k.sortQueue(now)
+	if _, waiting := k.queuePosition(nil); waiting >= k.queueSize {
+		return 0, ErrQueueFull
+	}
+
+	k.queue = append(k.queue, job)
+	k.jobs[job.orderID] = job
+	k.sortQueue(now)
+	k.cond.Broadcast()
+
+	position, _ := k.queuePosition(job)
+	return position, nil
+}
+
+// replaceJob swaps the items of a queued order for those of the given job,
+// keeping the order's place in the queue and, unless the job sets one, its
+// priority. It returns the order's new position
+// among the orders waiting for a station. Orders a station has started on can
+// no longer be changed.
+func (k *Kitchen) replaceJob(job *cookJob) (int, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if len(job.tasks) == 0 {
+		return 0, ErrNoPizzas
+	}
+	existing, ok := k.jobs[job.orderID]
+	if !ok {
+		return 0, ErrJobNotFound
+	}
+	if existing.status != JobQueued {
+		return 0, ErrJobStarted
+	}
+
+	job.queuedAt = existing.queuedAt
+	if job.priority == "" {
+		job.priority = existing.priority
+	}
+	for i, queued := range k.queue {
+		if queued == existing {
+			k.queue[i] = job
+			break
+		}
+	}
+	k.jobs[job.orderID] = job
+	existing.cancel()
+
+	now := time.Now()
+	k.sortQueue(now)
+	k.cond.Broadcast()
+
+	position, _ := k.queuePosition(job)
+	return position, nil
+}
+
+// queuePosition walks the sorted queue as the stations will, handing the
+// currently idle stations to the highest ranked orders. It returns the
+// position of the given job among the orders left waiting (0 if it gets a
+// station), and the total number of orders left waiting. The caller must hold
+// k.mu.
+func (k *Kitchen) queuePosition(target *cookJob) (int, int) {
+	idle := k.stations - k.busy
+	position, waiting := 0, 0
+	for _, job := range k.queue {
+		if job.next == 0 && idle <= 0 {
+			waiting++
+			if job == target {
+				position = waiting
+			}
+		}
+		idle -= len(job.tasks) - job.next
+	}
+	return position, waiting
+}
+
+// sortQueue orders the queue by rank, highest first, keeping FIFO order for
+// equal ranks. The caller must hold k.mu.
+func (k *Kitchen) sortQueue(now time.Time) {
+	sort.SliceStable(k.queue, func(i, j int) bool {
+		return k.rank(k.queue[i], now) > k.rank(k.queue[j], now)
+	})
+}
+
+// rank returns the job's effective priority: its priority level plus one
+// level for every aging interval it has spent in the queue.
+func (k *Kitchen) rank(job *cookJob, now time.Time) float64 {
+	waited := now.Sub(job.queuedAt)
+	return float64(priorityLevels[job.priority]) + float64(waited)/float64(k.agingInterval)
+}
+
+// jobFailed reports whether the job has failed, in which case its remaining
+// pizzas are not cooked.
+func (k *Kitchen) jobFailed(job *cookJob) bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return job.failed
+}
+
+// startStage records the stage the pizza has moved into.
+func (k *Kitchen) startStage(task *cookTask, stage string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	task.stage = stage
+}
+
+// advanceTask records one more second of cooking for the pizza.
+func (k *Kitchen) advanceTask(task *cookTask) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	task.elapsed++
+	task.job.doneSeconds++
+}
+
+// finishTask records a cooked pizza and reports whether it was the last
+// pizza of a job that has not failed.
+func (k *Kitchen) finishTask(task *cookTask) bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	now := time.Now()
+	task.status = ItemCooked
+	task.stage = ""
+	task.finishedAt = &now
+
+	job := task.job
+	job.remaining--
+	if job.remaining == 0 && !job.failed {
+		job.status = JobDone
+		job.finishedAt = &now
+		k.retire(job)
+		return true
+	}
+	return false
+}
+
+// retire forgets a finished job once the job retention period has passed,
+// unless the order has been sent to the kitchen again since. The caller must
+// hold k.mu.
+func (k *Kitchen) retire(job *cookJob) {
+	time.AfterFunc(k.jobRetention, func() {
+		k.mu.Lock()
+		defer k.mu.Unlock()
+		if k.jobs[job.orderID] == job {
+			delete(k.jobs, job.orderID)
+		}
+	})
+}
+
+// failJob marks the job as failed because of the given pizza, stops the
+// stations still working on it, drops its pizzas from the queue and sends a
+// FAILED event. Only the first failure of a job is reported, after any
+// progress event already on its way, so FAILED is the last event of the job.
+func (k *Kitchen) failJob(task *cookTask, reason string) {
+	job := task.job
+
+	k.mu.Lock()
+	if job.failed {
+		k.mu.Unlock()
+		return
+	}
+	now := time.Now()
+	job.failed = true
+	job.status = JobFailed
+	job.finishedAt = &now
+	k.retire(job)
+	task.status = ItemFailed
+	task.finishedAt = &now
+	for _, other := range job.tasks {
+		if other.status == ItemWaiting || other.status == ItemCooking {
+			other.status = ItemCancelled
+		}
+	}
+	for i, queued := range k.queue {
+		if queued == job {
+			k.queue = append(k.queue[:i:i], k.queue[i+1:]...)
+			break
+		}
+	}
+	k.mu.Unlock()
+
+	job.cancel()
+	slog.Warn("cooking failed", "orderId", job.orderID, "reason", reason)
+	job.reportMu.Lock()
+	defer job.reportMu.Unlock()
+	k.sendEvent(k.ctx, OrderEvent{OrderID: job.orderID, Status: "FAILED", Reason: reason})
+}
+
+// reportProgress sends the job's overall cooking percentage, along with the
+// stage of the pizza that advanced it, to the store when either has changed.
+// Completion is reported by the DONE event instead.
+func (k *Kitchen) reportProgress(job *cookJob, stage string) {
+	job.reportMu.Lock()
+	defer job.reportMu.Unlock()
+
+	k.mu.Lock()
+	percent := 0
+	if job.totalSeconds > 0 {
+		percent = job.doneSeconds * 100 / job.totalSeconds
+	}
+	failed := job.failed
+	k.mu.Unlock()
+
+	if failed || percent >= 100 || (percent == job.lastPercent && stage == job.lastStage) {
+		return
+	}
+	job.lastPercent = percent
+	job.lastStage = stage
+	k.sendEvent(k.ctx, OrderEvent{
+		OrderID:  job.orderID,
+		Status:   fmt.Sprintf("%s %d%%", stage, percent),
+		Stage:    stage,
+		Progress: percent,
+	})
+}
+
+// jobStatus returns a snapshot of the job. The caller must hold k.mu.
+func (k *Kitchen) jobStatus(job *cookJob) CookJobStatus {
+	status := CookJobStatus{
+		OrderID:    job.orderID,
+		Status:     job.status,
+		Priority:   job.priority,
+		Items:      make([]CookItemStatus, 0, len(job.tasks)),
+		QueuedAt:   job.queuedAt,
+		StartedAt:  job.startedAt,
+		FinishedAt: job.finishedAt,
+	}
+	if job.totalSeconds > 0 {
+		status.Progress = job.doneSeconds * 100 / job.totalSeconds
+	}
+	for _, task := range job.tasks {
+		item := CookItemStatus{
+			PizzaType:       task.pizzaType,
+			Status:          task.status,
+			Stage:           task.stage,
+			Station:         task.station,
+			OvenTemperature: task.ovenTemperature,
+			CookingTime:     task.cookingTime,
+			StartedAt:       task.startedAt,
+			FinishedAt:      task.finishedAt,
+		}
+		if task.cookingTime > 0 {
+			item.Progress = task.elapsed * 100 / task.cookingTime
+		}
+		status.Items = append(status.Items, item)
+	}
+	return status
+}
+
+// GetJob returns the status of the order's cook job.
+func (k *Kitchen) GetJob(orderID uuid.UUID) (CookJobStatus, bool) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	job, ok := k.jobs[orderID]
+	if !ok {
+		return CookJobStatus{}, false
+	}
+	return k.jobStatus(job), true
+}
+
+// ActiveJobs returns the status of all queued and cooking jobs, oldest first.
+func (k *Kitchen) ActiveJobs() []CookJobStatus {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	jobs := make([]CookJobStatus, 0)
+	for _, job := range k.jobs {
+		if job.status == JobQueued || job.status == JobCooking {
+			jobs = append(jobs, k.jobStatus(job))
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].QueuedAt.Before(jobs[j].QueuedAt) })
+	return jobs
+}
+
+// Stop stops all cook stations and cancels any cooking in progress.
+// Queued orders are discarded.
+func (k *Kitchen) Stop() {
+	k.mu.Lock()
+	k.stopped = true
+	k.queue = nil
+	k.mu.Unlock()
+
+	k.cond.Broadcast()
+	k.cancel()
+}
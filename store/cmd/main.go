@@ -10,6 +10,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
@@ -26,6 +27,45 @@ func main() {
 	}
 
 	s := store.NewStore()
+	s.SetRedeliveryPolicy(
+		envInt("STORE_MAX_DELIVERY_ATTEMPTS", store.DefaultMaxDeliveryAttempts),
+		time.Duration(envInt("STORE_REDELIVERY_DELAY", int(store.DefaultRedeliveryDelay.Seconds())))*time.Second,
+	)
+	s.SetKitchenRetryPolicy(
+		envInt("STORE_MAX_KITCHEN_ATTEMPTS", store.DefaultMaxKitchenAttempts),
+		time.Duration(envInt("STORE_KITCHEN_RETRY_DELAY", int(store.DefaultKitchenRetryDelay.Seconds())))*time.Second,
+	)
+
+	// Take payments with the fake provider, configured to succeed, decline or time out
+	paymentConfig := store.DefaultFakePaymentConfig()
+	if mode := os.Getenv("STORE_PAYMENT_MODE"); mode != "" {
+		paymentConfig.Mode = mode
+	}
+	paymentConfig.Timeout = time.Duration(envInt("STORE_PAYMENT_TIMEOUT", int(store.DefaultPaymentTimeout.Seconds()))) * time.Second
+	s.SetPaymentProvider(store.NewFakePaymentProvider(paymentConfig))
+
+	// Stage SLAs in seconds, used to flag late orders
+	slas := store.DefaultSLAs()
+	for stage, key := range map[string]string{
+		store.StagePending:  "STORE_SLA_PENDING",
+		store.StageCooking:  "STORE_SLA_COOKING",
+		store.StageReady:    "STORE_SLA_READY",
+		store.StageDelivery: "STORE_SLA_DELIVERY",
+	} {
+		slas[stage] = time.Duration(envInt(key, int(slas[stage].Seconds()))) * time.Second
+	}
+	s.SetSLAs(slas)
+
+	// Estimates in seconds used to send scheduled orders to the kitchen in time
+	s.SetScheduleEstimates(
+		time.Duration(envInt("STORE_COOK_ESTIMATE", int(store.DefaultCookEstimate.Seconds())))*time.Second,
+		time.Duration(envInt("STORE_DELIVERY_ESTIMATE", int(store.DefaultDeliveryEstimate.Seconds())))*time.Second,
+	)
+	s.SetEstimateTimeout(time.Duration(envInt("STORE_ESTIMATE_TIMEOUT", int(store.DefaultEstimateTimeout.Seconds()))) * time.Second)
+
+	// Token WebSocket clients send to follow every order on the admin channel
+	s.SetAdminToken(os.Getenv("STORE_ADMIN_TOKEN"))
+
 	r := chi.NewRouter()
 
 	// Middleware
@@ -33,10 +73,34 @@ func main() {
 	r.Use(middleware.Recoverer)
 
 	// REST endpoints
-	r.Post("/order", s.HandleCreateOrder) // Create a new pizza order
-	r.Get("/orders", s.HandleGetOrders)   // Get all orders
-	r.Post("/events", s.HandleEvent)      // Receive events from kitchen/delivery
-	r.Get("/events", s.HandleGetEvents)   // Get events for an order
+	r.Post("/order", s.HandleCreateOrder)                        // Create a new pizza order
+	r.Get("/orders", s.HandleGetOrders)                          // List orders with filters and pagination
+	r.Get("/orders/late", s.HandleGetLateOrders)                 // Orders running late
+	r.Get("/orders/stream", s.HandleOrderStream)                 // Order updates as Server-Sent Events
+	r.Get("/order/{orderId}", s.HandleGetOrder)                  // Get an order with its events
+	r.Patch("/order/{orderId}", s.HandleUpdateOrder)             // Change the items of an order before it is cooked
+	r.Delete("/order/{orderId}", s.HandleCancelOrder)            // Cancel a scheduled order
+	r.Put("/order/{orderId}/schedule", s.HandleRescheduleOrder)  // Change when a scheduled order is delivered
+	r.Get("/order/{orderId}/location", s.HandleGetOrderLocation) // Latest driver location for an order
+	r.Post("/order/{orderId}/reorder", s.HandleReorder)          // Place a past order again
+	r.Post("/events", s.HandleEvent)                             // Receive events from kitchen/delivery
+	r.Get("/events", s.HandleGetEvents)                          // Get events for an order
+
+	// Customer endpoints
+	r.Post("/customers", s.HandleCreateCustomer)                            // Register a customer
+	r.Get("/customers/{customerId}", s.HandleGetCustomer)                   // Get a customer
+	r.Post("/customers/{customerId}/addresses", s.HandleAddCustomerAddress) // Save a delivery address
+	r.Get("/customers/{customerId}/orders", s.HandleGetCustomerOrders)      // A customer's order history
+
+	// Promotion admin endpoints
+	r.Group(func(r chi.Router) {
+		r.Use(s.RequireAdmin)
+		r.Post("/promotions", s.HandleCreatePromotion)          // Add a promo code
+		r.Get("/promotions", s.HandleGetPromotions)             // List promo codes
+		r.Get("/promotions/{code}", s.HandleGetPromotion)       // Get a promo code
+		r.Put("/promotions/{code}", s.HandleUpdatePromotion)    // Replace a promo code's terms
+		r.Delete("/promotions/{code}", s.HandleDeletePromotion) // Retire a promo code
+	})
 
 	// WebSocket endpoint
 	r.Get("/ws", s.HandleWebSocket) // Real-time order updates
@@ -57,6 +121,8 @@ func main() {
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
+	s.StartSLAMonitor(ctx, store.DefaultSLACheckInterval)
+
 	go func() {
 		slog.Info("store service starting", "addr", addr)
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -77,3 +143,18 @@ func main() {
 	}
 	slog.Info("store service stopped")
 }
+
+// envInt reads an integer from the environment, falling back to def when the
+// variable is unset or invalid.
+func envInt(key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		slog.Warn("invalid integer in environment, using default", "key", key, "value", v, "default", def)
+		return def
+	}
+	return n
+}
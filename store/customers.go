@@ -0,0 +1,232 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// DefaultOrderPageSize is the number of orders returned per page of a
+// customer's order history when no limit is given.
+const DefaultOrderPageSize = 20
+
+// MaxOrderPageSize is the largest page of orders a client can ask for.
+const MaxOrderPageSize = 100
+
+// Customer is a person who orders pizzas, with the addresses they saved for
+// delivery.
+type Customer struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	Phone     string    `json:"phone,omitempty"`
+	Addresses []Address `json:"addresses"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// CreateCustomerRequest represents the request body for creating a customer.
+type CreateCustomerRequest struct {
+	Name      string    `json:"name"`
+	Phone     string    `json:"phone,omitempty"`
+	Addresses []Address `json:"addresses,omitempty"`
+}
+
+// GetCustomer retrieves a copy of a customer by ID.
+func (s *Store) GetCustomer(customerID uuid.UUID) (Customer, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	customer, exists := s.customers[customerID]
+	if !exists {
+		return Customer{}, false
+	}
+	found := *customer
+	found.Addresses = append([]Address(nil), customer.Addresses...)
+	return found, true
+}
+
+// HandleCreateCustomer handles POST /customers requests to register a customer.
+func (s *Store) HandleCreateCustomer(w http.ResponseWriter, r *http.Request) {
+	var req CreateCustomerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if req.Name == "" {
+		http.Error(w, "Customer name is required", http.StatusBadRequest)
+		return
+	}
+	for _, address := range req.Addresses {
+		if !address.Valid() {
+			http.Error(w, "Invalid address", http.StatusBadRequest)
+			return
+		}
+	}
+
+	customer := &Customer{
+		ID:        uuid.New(),
+		Name:      req.Name,
+		Phone:     req.Phone,
+		Addresses: req.Addresses,
+		CreatedAt: time.Now(),
+	}
+	if customer.Addresses == nil {
+		customer.Addresses = []Address{}
+	}
+
+	s.mu.Lock()
+	s.customers[customer.ID] = customer
+	s.mu.Unlock()
+
+	slog.Info("customer created", "customerId", customer.ID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(customer); err != nil {
+		slog.Error("failed to encode customer", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleGetCustomer handles GET /customers/{customerId} requests.
+func (s *Store) HandleGetCustomer(w http.ResponseWriter, r *http.Request) {
+	customerID, err := uuid.Parse(chi.URLParam(r, "customerId"))
+	if err != nil {
+		http.Error(w, "Invalid customerId format", http.StatusBadRequest)
+		return
+	}
+
+	customer, exists := s.GetCustomer(customerID)
+	if !exists {
+		http.Error(w, "Customer not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(customer); err != nil {
+		slog.Error("failed to encode customer", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleAddCustomerAddress handles POST /customers/{customerId}/addresses
+// requests to save another delivery address for a customer.
+func (s *Store) HandleAddCustomerAddress(w http.ResponseWriter, r *http.Request) {
+	customerID, err := uuid.Parse(chi.URLParam(r, "customerId"))
+	if err != nil {
+		http.Error(w, "Invalid customerId format", http.StatusBadRequest)
+		return
+	}
+
+	var address Address
+	if err := json.NewDecoder(r.Body).Decode(&address); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if !address.Valid() {
+		http.Error(w, "Invalid address", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	customer, exists := s.customers[customerID]
+	if exists {
+		customer.Addresses = append(customer.Addresses, address)
+	}
+	s.mu.Unlock()
+	if !exists {
+		http.Error(w, "Customer not found", http.StatusNotFound)
+		return
+	}
+
+	updated, _ := s.GetCustomer(customerID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(updated); err != nil {
+		slog.Error("failed to encode customer", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleGetCustomerOrders handles GET /customers/{customerId}/orders requests.
+// It takes the same query parameters as GET /orders and returns a page of the
+// customer's orders, newest first, with the cursor of the next page in the
+// X-Next-Cursor header.
+func (s *Store) HandleGetCustomerOrders(w http.ResponseWriter, r *http.Request) {
+	customerID, err := uuid.Parse(chi.URLParam(r, "customerId"))
+	if err != nil {
+		http.Error(w, "Invalid customerId format", http.StatusBadRequest)
+		return
+	}
+
+	q, err := parseOrderQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid query: %v", err), http.StatusBadRequest)
+		return
+	}
+	q.customerID = &customerID
+	if q.limit == 0 {
+		q.limit = DefaultOrderPageSize
+	}
+
+	if _, exists := s.GetCustomer(customerID); !exists {
+		http.Error(w, "Customer not found", http.StatusNotFound)
+		return
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	s.writeOrderPage(w, q)
+}
+
+// HandleReorder handles POST /order/{orderId}/reorder requests. It places a
+// new order with the items, priority, delivery address, customer and tip of a
+// past order, priced at today's prices. Promo codes are not carried over.
+func (s *Store) HandleReorder(w http.ResponseWriter, r *http.Request) {
+	orderID, err := uuid.Parse(chi.URLParam(r, "orderId"))
+	if err != nil {
+		http.Error(w, "Invalid orderId format", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.RLock()
+	past, exists := s.orders[orderID]
+	var req CreateOrderRequest
+	if exists {
+		req = CreateOrderRequest{
+			OrderItems:      append([]OrderItem(nil), past.OrderItems...),
+			OrderData:       past.OrderData,
+			Priority:        past.Priority,
+			DeliveryAddress: past.DeliveryAddress,
+			Tip:             past.Pricing.Tip,
+			CustomerID:      past.CustomerID,
+		}
+	}
+	s.mu.RUnlock()
+	if !exists {
+		http.Error(w, "Order not found", http.StatusNotFound)
+		return
+	}
+
+	order, err := s.placeOrder(r.Context(), req)
+	if err != nil {
+		writeOrderError(w, err)
+		return
+	}
+	slog.Info("order reordered", "orderId", order.OrderID, "from", orderID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(order); err != nil {
+		slog.Error("failed to encode order", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,203 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// addCustomer registers a customer with the store and returns their ID.
+func addCustomer(store *Store, name string) uuid.UUID {
+	customer := &Customer{ID: uuid.New(), Name: name, Addresses: []Address{}, CreatedAt: time.Now()}
+	store.mu.Lock()
+	store.customers[customer.ID] = customer
+	store.mu.Unlock()
+	return customer.ID
+}
+
+// customersRouter returns a router with the order and customer endpoints of the store.
+func customersRouter(store *Store) *chi.Mux {
+	router := chi.NewRouter()
+	router.Post("/order", store.HandleCreateOrder)
+	router.Post("/order/{orderId}/reorder", store.HandleReorder)
+	router.Post("/customers", store.HandleCreateCustomer)
+	router.Get("/customers/{customerId}", store.HandleGetCustomer)
+	router.Post("/customers/{customerId}/addresses", store.HandleAddCustomerAddress)
+	router.Get("/customers/{customerId}/orders", store.HandleGetCustomerOrders)
+	return router
+}
+
+// TestCreateAndGetCustomer verifies that a customer can be registered with saved addresses and
+// retrieved by ID.
+func TestCreateAndGetCustomer(t *testing.T) {
+	store := NewStore()
+	router := customersRouter(store)
+
+	rec := sendJSON(router, http.MethodPost, "/customers", CreateCustomerRequest{
+		Name:  "Ada Lovelace",
+		Phone: "+34 600 000 000",
+		Addresses: []Address{
+			{Street: "Carrer de Mallorca 401", City: "Barcelona", Lat: 41.4036, Lon: 2.1744},
+		},
+	})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status 201 Created, got %d", rec.Code)
+	}
+	var created Customer
+	json.NewDecoder(rec.Body).Decode(&created)
+
+	rec = sendJSON(router, http.MethodPost, "/customers/"+created.ID.String()+"/addresses",
+		Address{Street: "Passeig de Gràcia 43", City: "Barcelona", Lat: 41.3917, Lon: 2.1649})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status 201 Created when saving an address, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/customers/"+created.ID.String(), nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200 OK, got %d", rec.Code)
+	}
+	var got Customer
+	json.NewDecoder(rec.Body).Decode(&got)
+	if got.Name != "Ada Lovelace" || got.Phone != "+34 600 000 000" || len(got.Addresses) != 2 {
+		t.Errorf("unexpected customer: %+v", got)
+	}
+}
+
+// TestCreateCustomerValidation verifies that customers without a name or with invalid addresses
+// are rejected, and that unknown customers are not found.
+func TestCreateCustomerValidation(t *testing.T) {
+	store := NewStore()
+	router := customersRouter(store)
+
+	if rec := sendJSON(router, http.MethodPost, "/customers", CreateCustomerRequest{}); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 Bad Request without a name, got %d", rec.Code)
+	}
+	invalid := CreateCustomerRequest{Name: "Ada", Addresses: []Address{{Street: "", Lat: 41.4, Lon: 2.17}}}
+	if rec := sendJSON(router, http.MethodPost, "/customers", invalid); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 Bad Request for an invalid address, got %d", rec.Code)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/customers/"+uuid.New().String(), nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 Not Found, got %d", rec.Code)
+	}
+}
+
+// TestPostOrderUnknownCustomer verifies that orders for unknown customers are rejected.
+func TestPostOrderUnknownCustomer(t *testing.T) {
+	store := NewStore()
+	router := customersRouter(store)
+
+	unknown := uuid.New()
+	rec := sendJSON(router, http.MethodPost, "/order", CreateOrderRequest{
+		OrderItems: []OrderItem{{PizzaType: "Margherita", Quantity: 1}},
+		CustomerID: &unknown,
+	})
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 Bad Request, got %d", rec.Code)
+	}
+}
+
+// TestGetCustomerOrdersPaginates verifies that a customer's orders are returned newest first, a
+// page at a time with the cursor of the next page in X-Next-Cursor, and without other customers' orders.
+func TestGetCustomerOrdersPaginates(t *testing.T) {
+	store := NewStore()
+	router := customersRouter(store)
+	alice := addCustomer(store, "Alice")
+	bob := addCustomer(store, "Bob")
+
+	for i := 1; i <= 5; i++ {
+		sendJSON(router, http.MethodPost, "/order", CreateOrderRequest{
+			OrderItems: []OrderItem{{PizzaType: "Margherita", Quantity: i}},
+			CustomerID: &alice,
+		})
+	}
+	sendJSON(router, http.MethodPost, "/order", CreateOrderRequest{
+		OrderItems: []OrderItem{{PizzaType: "Pepperoni", Quantity: 1}},
+		CustomerID: &bob,
+	})
+
+	var quantities []int
+	pages := 0
+	path := fmt.Sprintf("/customers/%s/orders?limit=2", alice)
+	for path != "" {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200 OK, got %d", rec.Code)
+		}
+		var orders []Order
+		json.NewDecoder(rec.Body).Decode(&orders)
+		for _, order := range orders {
+			quantities = append(quantities, order.OrderItems[0].Quantity)
+		}
+		pages++
+		path = ""
+		if cursor := rec.Header().Get("X-Next-Cursor"); cursor != "" {
+			path = fmt.Sprintf("/customers/%s/orders?limit=2&cursor=%s", alice, cursor)
+		}
+	}
+	if pages != 3 {
+		t.Errorf("expected 3 pages, got %d", pages)
+	}
+	if fmt.Sprint(quantities) != "[5 4 3 2 1]" {
+		t.Errorf("expected Alice's orders newest first, got quantities %v", quantities)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/customers/%s/orders?limit=-1", alice), nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 Bad Request for an invalid limit, got %d", rec.Code)
+	}
+}
+
+// TestReorderClonesPastOrder verifies that a past order can be placed again as a new order.
+func TestReorderClonesPastOrder(t *testing.T) {
+	store := NewStore()
+	router := customersRouter(store)
+	alice := addCustomer(store, "Alice")
+
+	rec := sendJSON(router, http.MethodPost, "/order", CreateOrderRequest{
+		OrderItems: []OrderItem{{PizzaType: "Hawaiian", Quantity: 2}},
+		Priority:   PriorityExpress,
+		Tip:        200,
+		CustomerID: &alice,
+	})
+	var past Order
+	json.NewDecoder(rec.Body).Decode(&past)
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/order/"+past.OrderID.String()+"/reorder", nil))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status 201 Created, got %d", rec.Code)
+	}
+	var reordered Order
+	json.NewDecoder(rec.Body).Decode(&reordered)
+
+	if reordered.OrderID == past.OrderID {
+		t.Error("expected a new order ID")
+	}
+	if reordered.CustomerID == nil || *reordered.CustomerID != alice {
+		t.Errorf("expected the reorder to belong to the same customer, got %v", reordered.CustomerID)
+	}
+	if len(reordered.OrderItems) != 1 || reordered.OrderItems[0] != past.OrderItems[0] {
+		t.Errorf("expected the same items, got %+v", reordered.OrderItems)
+	}
+	if reordered.Priority != PriorityExpress || reordered.Pricing.Total != past.Pricing.Total {
+		t.Errorf("expected the same priority and total, got %s and %d", reordered.Priority, reordered.Pricing.Total)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/order/"+uuid.New().String()+"/reorder", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 Not Found for an unknown order, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,141 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// DefaultEstimateTimeout is how long a new order waits for the kitchen's
+// estimate before the store's cook estimate is used instead.
+const DefaultEstimateTimeout = 2 * time.Second
+
+// CookEstimate is the kitchen's estimate of how long an order would wait for
+// a cook station and take to cook, in seconds.
+type CookEstimate struct {
+	WaitSeconds  int `json:"waitSeconds"`
+	CookSeconds  int `json:"cookSeconds"`
+	ReadySeconds int `json:"readySeconds"`
+}
+
+// estimateCooking asks the kitchen how long an order with the given items and
+// priority would take to be ready if it were sent now. The kitchen has the
+// estimate timeout to answer, as the order waits for the estimate.
+func (s *Store) estimateCooking(ctx context.Context, items []OrderItem, priority string) (CookEstimate, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.estimateTimeout)
+	defer cancel()
+
+	body, err := json.Marshal(CookRequest{OrderItems: items, Priority: priority})
+	if err != nil {
+		return CookEstimate{}, fmt.Errorf("marshaling estimate request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.kitchenURL+"/cook/estimate", bytes.NewReader(body))
+	if err != nil {
+		return CookEstimate{}, fmt.Errorf("creating estimate request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return CookEstimate{}, fmt.Errorf("calling kitchen service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return CookEstimate{}, fmt.Errorf("kitchen service returned status %d", resp.StatusCode)
+	}
+
+	var estimate CookEstimate
+	if err := json.NewDecoder(resp.Body).Decode(&estimate); err != nil {
+		return CookEstimate{}, fmt.Errorf("decoding estimate: %w", err)
+	}
+	return estimate, nil
+}
+
+// SetEstimateTimeout sets how long a new order waits for the kitchen's
+// estimate before the store's cook estimate is used instead.
+func (s *Store) SetEstimateTimeout(timeout time.Duration) {
+	s.estimateTimeout = timeout
+}
+
+// estimateOrder sets when a new order is expected to be ready and delivered.
+// Orders sent to the kitchen now are estimated by the kitchen from its queue
+// and cooking profiles, falling back to the store's cook estimate when the
+// kitchen cannot be asked; scheduled orders are expected at their time.
+func (s *Store) estimateOrder(ctx context.Context, order *Order, deliveryTime time.Duration) {
+	order.cookTime = s.cookEstimate
+	order.deliveryTime = deliveryTime
+
+	if order.ScheduledFor != nil {
+		deliveryAt := *order.ScheduledFor
+		readyAt := deliveryAt.Add(-deliveryTime)
+		order.EstimatedReadyAt, order.EstimatedDeliveryAt = &readyAt, &deliveryAt
+		return
+	}
+
+	readyIn := s.cookEstimate
+	estimate, err := s.estimateCooking(ctx, order.OrderItems, order.Priority)
+	if err != nil {
+		slog.Warn("failed to estimate cooking, using the default estimate", "orderId", order.OrderID, "error", err)
+	} else {
+		order.cookTime = time.Duration(estimate.CookSeconds) * time.Second
+		readyIn = time.Duration(estimate.ReadySeconds) * time.Second
+	}
+
+	readyAt := order.CreatedAt.Add(readyIn)
+	deliveryAt := readyAt.Add(deliveryTime)
+	order.EstimatedReadyAt, order.EstimatedDeliveryAt = &readyAt, &deliveryAt
+}
+
+// reviseETA revises when an order is expected to be ready and delivered from
+// a kitchen or delivery event, and returns the revised estimates to push to
+// WebSocket clients. Cooking progress is extrapolated from the time cooking
+// has taken so far, and delivery progress from the driver's ETA. It returns
+// nil estimates when the event does not revise them.
+func (s *Store) reviseETA(event OrderEvent, status string, now time.Time) (*time.Time, *time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	order, exists := s.orders[event.OrderID]
+	if !exists {
+		return nil, nil
+	}
+	cookTime, deliveryTime := order.cookTime, order.deliveryTime
+	if cookTime == 0 {
+		cookTime = s.cookEstimate
+	}
+	if deliveryTime == 0 {
+		deliveryTime = s.deliveryEstimate
+	}
+
+	var readyAt, deliveryAt time.Time
+	switch {
+	case status == "COOKED":
+		readyAt = now
+		deliveryAt = now.Add(deliveryTime)
+	case event.Source == "kitchen" && status != "FAILED":
+		readyAt = now.Add(cookTime)
+		if startedAt := order.Timeline.CookingStartedAt; event.Progress > 0 && startedAt != nil {
+			elapsed := now.Sub(*startedAt)
+			readyAt = now.Add(elapsed * time.Duration(100-event.Progress) / time.Duration(event.Progress))
+		}
+		deliveryAt = readyAt.Add(deliveryTime)
+	case status == "DELIVERED":
+		deliveryAt = now
+	case event.Source == "delivery" && event.Location != nil && status != StatusDeliveryFailed:
+		deliveryAt = now.Add(time.Duration(event.Location.ETASeconds) * time.Second)
+	default:
+		return nil, nil
+	}
+
+	if !readyAt.IsZero() {
+		order.EstimatedReadyAt = &readyAt
+	}
+	order.EstimatedDeliveryAt = &deliveryAt
+	return order.EstimatedReadyAt, order.EstimatedDeliveryAt
+}
@@ -0,0 +1,183 @@
+package store
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+// TestPostOrderEstimatesReadyAndDeliveryTimes verifies that a new order is estimated to be ready
+// after the kitchen's estimate and delivered after the delivery estimate, falling back to the
+// store's cook estimate when the kitchen cannot be asked.
+func TestPostOrderEstimatesReadyAndDeliveryTimes(t *testing.T) {
+	kitchenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/cook/estimate" {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		var req CookRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Priority != PriorityExpress || len(req.OrderItems) != 1 {
+			t.Errorf("expected the order's items and priority to be estimated, got %+v", req)
+		}
+		json.NewEncoder(w).Encode(CookEstimate{WaitSeconds: 10, CookSeconds: 20, ReadySeconds: 30})
+	}))
+	defer kitchenServer.Close()
+
+	placeOrder := func(kitchenURL string) Order {
+		store := NewStore()
+		store.SetKitchenURL(kitchenURL)
+		store.SetScheduleEstimates(45*time.Second, 15*time.Second)
+		router := chi.NewRouter()
+		router.Post("/order", store.HandleCreateOrder)
+
+		rec := sendJSON(router, http.MethodPost, "/order", CreateOrderRequest{
+			OrderItems: []OrderItem{{PizzaType: "Margherita", Quantity: 2}},
+			Priority:   PriorityExpress,
+		})
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected status 201 Created, got %d", rec.Code)
+		}
+		var order Order
+		json.NewDecoder(rec.Body).Decode(&order)
+		if order.EstimatedReadyAt == nil || order.EstimatedDeliveryAt == nil {
+			t.Fatalf("expected estimated times, got %+v", order)
+		}
+		return order
+	}
+
+	order := placeOrder(kitchenServer.URL)
+	if got := order.EstimatedReadyAt.Sub(order.CreatedAt); got != 30*time.Second {
+		t.Errorf("expected the order to be ready in 30s, got %s", got)
+	}
+	if got := order.EstimatedDeliveryAt.Sub(*order.EstimatedReadyAt); got != 15*time.Second {
+		t.Errorf("expected the order to be delivered 15s after it is ready, got %s", got)
+	}
+
+	order = placeOrder("http://127.0.0.1:0")
+	if got := order.EstimatedReadyAt.Sub(order.CreatedAt); got != 45*time.Second {
+		t.Errorf("expected the store's cook estimate of 45s, got %s", got)
+	}
+}
+
+// TestPostOrderDoesNotWaitForSlowEstimate verifies that an order is placed with the store's cook
+// estimate when the kitchen does not answer the estimate request within the estimate timeout.
+func TestPostOrderDoesNotWaitForSlowEstimate(t *testing.T) {
+	release := make(chan struct{})
+	kitchenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/cook/estimate" {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer kitchenServer.Close()
+	defer close(release)
+
+	store := NewStore()
+	store.SetKitchenURL(kitchenServer.URL)
+	store.SetScheduleEstimates(45*time.Second, 15*time.Second)
+	store.SetEstimateTimeout(50 * time.Millisecond)
+	router := chi.NewRouter()
+	router.Post("/order", store.HandleCreateOrder)
+
+	start := time.Now()
+	rec := sendJSON(router, http.MethodPost, "/order", CreateOrderRequest{
+		OrderItems: []OrderItem{{PizzaType: "Margherita", Quantity: 1}},
+	})
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the order to be placed without waiting for the kitchen, took %s", elapsed)
+	}
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status 201 Created, got %d", rec.Code)
+	}
+	var order Order
+	json.NewDecoder(rec.Body).Decode(&order)
+	if order.EstimatedReadyAt == nil {
+		t.Fatalf("expected an estimated ready time, got %+v", order)
+	}
+	if got := order.EstimatedReadyAt.Sub(order.CreatedAt); got != 45*time.Second {
+		t.Errorf("expected the store's cook estimate of 45s, got %s", got)
+	}
+}
+
+// TestEventsReviseEstimates verifies that kitchen and delivery events revise an order's
+// estimated times and that the revisions are pushed to WebSocket clients.
+func TestEventsReviseEstimates(t *testing.T) {
+	store := NewStore()
+	store.SetDeliveryURL("http://127.0.0.1:0")
+	router := chi.NewRouter()
+	router.Post("/events", store.HandleEvent)
+	router.Get("/ws", store.HandleWebSocket)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws?clientId=eta-client", nil)
+	if err != nil {
+		t.Fatalf("failed to connect to WebSocket: %v", err)
+	}
+	defer conn.Close()
+
+	order := &Order{OrderID: uuid.New(), OrderStatus: "pending", CreatedAt: time.Now(), cookTime: 20 * time.Second, deliveryTime: 10 * time.Second}
+	store.orders[order.OrderID] = order
+	subscribe(t, store, conn, "eta-client", SubscriptionMessage{Action: ActionSubscribe, OrderIDs: []uuid.UUID{order.OrderID}})
+
+	// revise posts an event and returns the estimates pushed to WebSocket clients
+	revise := func(event OrderEvent) WebSocketEvent {
+		t.Helper()
+		event.OrderID = order.OrderID
+		postEvent(router, event)
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		var pushed WebSocketEvent
+		if err := conn.ReadJSON(&pushed); err != nil {
+			t.Fatalf("failed to read WebSocket event: %v", err)
+		}
+		if pushed.EstimatedDeliveryAt == nil {
+			t.Fatalf("expected a revised delivery estimate for %s, got %+v", event.Status, pushed)
+		}
+		return pushed
+	}
+	near := func(got *time.Time, want time.Duration) bool {
+		return got != nil && (time.Until(*got)-want).Abs() < time.Second
+	}
+
+	pushed := revise(OrderEvent{Status: "prep 0%", Source: "kitchen"})
+	if !near(pushed.EstimatedReadyAt, 20*time.Second) || !near(pushed.EstimatedDeliveryAt, 30*time.Second) {
+		t.Errorf("expected the expected cooking time once cooking starts, got %v / %v", pushed.EstimatedReadyAt, pushed.EstimatedDeliveryAt)
+	}
+
+	store.mu.Lock()
+	startedAt := time.Now().Add(-30 * time.Second)
+	order.Timeline.CookingStartedAt = &startedAt
+	store.mu.Unlock()
+	pushed = revise(OrderEvent{Status: "baking 75%", Source: "kitchen", Progress: 75})
+	if !near(pushed.EstimatedReadyAt, 10*time.Second) {
+		t.Errorf("expected 10s left after 30s at 75%%, got %v", pushed.EstimatedReadyAt)
+	}
+
+	pushed = revise(OrderEvent{Status: "DONE", Source: "kitchen"})
+	if !near(pushed.EstimatedReadyAt, 0) || !near(pushed.EstimatedDeliveryAt, 10*time.Second) {
+		t.Errorf("expected delivery 10s after cooking, got %v / %v", pushed.EstimatedReadyAt, pushed.EstimatedDeliveryAt)
+	}
+
+	pushed = revise(OrderEvent{Status: "delivering 50%", Source: "delivery", Location: &Location{ETASeconds: 42}})
+	if !near(pushed.EstimatedDeliveryAt, 42*time.Second) {
+		t.Errorf("expected the driver's ETA of 42s, got %v", pushed.EstimatedDeliveryAt)
+	}
+
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	if order.EstimatedDeliveryAt == nil || !order.EstimatedDeliveryAt.Equal(*pushed.EstimatedDeliveryAt) {
+		t.Errorf("expected the order to keep the revised estimate, got %v", order.EstimatedDeliveryAt)
+	}
+}
@@ -4,41 +4,114 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"slices"
+	"strconv"
 	"sync"
 	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 )
 
 // CreateOrderRequest represents the request body for creating a new order.
+// Priority is optional and defaults to normal. Orders without a delivery
+// address are delivered in a random time. Tip is in cents. PromoCode applies a
+// promotion to the order, and CustomerID adds the order to a customer's order
+// history and identifies them for promotions limited per customer.
+// ScheduledFor asks for the order to be delivered at a later time.
 type CreateOrderRequest struct {
-	OrderItems []OrderItem `json:"orderItems"`
-	OrderData  string      `json:"orderData"`
+	OrderItems      []OrderItem `json:"orderItems"`
+	OrderData       string      `json:"orderData"`
+	Priority        string      `json:"priority,omitempty"`
+	DeliveryAddress *Address    `json:"deliveryAddress,omitempty"`
+	Tip             int         `json:"tip,omitempty"`
+	PromoCode       string      `json:"promoCode,omitempty"`
+	CustomerID      *uuid.UUID  `json:"customerId,omitempty"`
+	ScheduledFor    *time.Time  `json:"scheduledFor,omitempty"`
 }
 
 // CookRequest represents the request sent to the kitchen service.
 type CookRequest struct {
 	OrderID    uuid.UUID   `json:"orderId"`
 	OrderItems []OrderItem `json:"orderItems"`
+	Priority   string      `json:"priority,omitempty"`
 }
 
 // DeliverRequest represents the request sent to the delivery service.
 type DeliverRequest struct {
-	OrderID    uuid.UUID   `json:"orderId"`
-	OrderItems []OrderItem `json:"orderItems"`
+	OrderID         uuid.UUID   `json:"orderId"`
+	OrderItems      []OrderItem `json:"orderItems"`
+	Priority        string      `json:"priority,omitempty"`
+	DeliveryAddress *Address    `json:"deliveryAddress,omitempty"`
 }
 
+// ErrNotServiceable is returned when the delivery service does not deliver to an address.
+var ErrNotServiceable = errors.New("address is outside the delivery zones")
+
+// QuoteRequest represents the request sent to the delivery service to quote a delivery.
+type QuoteRequest struct {
+	DeliveryAddress Address `json:"deliveryAddress"`
+}
+
+// DeliveryQuote is the delivery service's offer for an address: its delivery
+// zone, the fee in minor currency units and the delivery times in seconds.
+type DeliveryQuote struct {
+	Zone             string `json:"zone"`
+	Fee              int    `json:"fee"`
+	MaxDeliveryTime  int    `json:"maxDeliveryTime"`
+	EstimatedSeconds int    `json:"estimatedSeconds"`
+}
+
+// Default redelivery policy for failed deliveries.
+const (
+	DefaultMaxDeliveryAttempts = 3
+	DefaultRedeliveryDelay     = 30 * time.Second
+)
+
+// Default policy for cook requests turned away because the kitchen is at
+// capacity: how many times an order is sent before it fails, and how long to
+// wait before sending it again when the kitchen gives no Retry-After.
+const (
+	DefaultMaxKitchenAttempts = 5
+	DefaultKitchenRetryDelay  = 10 * time.Second
+)
+
+// ErrKitchenAtCapacity is returned when the kitchen's queue is full. The
+// kitchen says how long to wait before trying again.
+var ErrKitchenAtCapacity = errors.New("kitchen is at capacity")
+
 // Store manages pizza orders and provides HTTP handlers for the store service.
 type Store struct {
 	mu          sync.RWMutex
 	orders      map[uuid.UUID]*Order
 	events      map[uuid.UUID][]OrderEvent
+	locations   map[uuid.UUID]*OrderLocation
 	hub         *WebSocketHub
+	adminToken  string
 	kitchenURL  string
 	deliveryURL string
 	httpClient  *http.Client
+
+	maxDeliveryAttempts int
+	redeliveryDelay     time.Duration
+	maxKitchenAttempts  int
+	kitchenRetryDelay   time.Duration
+
+	menu       map[string]int
+	taxRules   []TaxRule
+	promotions map[string]*Promotion
+	payments   PaymentProvider
+	customers  map[uuid.UUID]*Customer
+	slas       map[string]time.Duration
+
+	schedules        map[uuid.UUID]*time.Timer
+	cookEstimate     time.Duration
+	deliveryEstimate time.Duration
+	estimateTimeout  time.Duration
 }
 
 // NewStore creates a new Store instance with initialized order storage and WebSocket hub.
@@ -46,12 +119,27 @@ func NewStore() *Store {
 	return &Store{
 		orders:      make(map[uuid.UUID]*Order),
 		events:      make(map[uuid.UUID][]OrderEvent),
+		locations:   make(map[uuid.UUID]*OrderLocation),
 		hub:         NewWebSocketHub(),
 		kitchenURL:  "http://kitchen:8081",
 		deliveryURL: "http://delivery:8082",
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		maxDeliveryAttempts: DefaultMaxDeliveryAttempts,
+		redeliveryDelay:     DefaultRedeliveryDelay,
+		maxKitchenAttempts:  DefaultMaxKitchenAttempts,
+		kitchenRetryDelay:   DefaultKitchenRetryDelay,
+		menu:                DefaultMenu(),
+		taxRules:            DefaultTaxRules(),
+		promotions:          make(map[string]*Promotion),
+		payments:            NewFakePaymentProvider(DefaultFakePaymentConfig()),
+		customers:           make(map[uuid.UUID]*Customer),
+		slas:                DefaultSLAs(),
+		schedules:           make(map[uuid.UUID]*time.Timer),
+		cookEstimate:        DefaultCookEstimate,
+		deliveryEstimate:    DefaultDeliveryEstimate,
+		estimateTimeout:     DefaultEstimateTimeout,
 	}
 }
 
@@ -65,8 +153,31 @@ func (s *Store) SetDeliveryURL(url string) {
 	s.deliveryURL = url
 }
 
+// SetRedeliveryPolicy sets how many times an order is handed to the delivery
+// service before it is marked for refund, and how long the store waits before
+// trying again when the customer was not reached.
+func (s *Store) SetRedeliveryPolicy(maxAttempts int, delay time.Duration) {
+	s.maxDeliveryAttempts = maxAttempts
+	s.redeliveryDelay = delay
+}
+
+// SetKitchenRetryPolicy sets how many times an order is sent to a kitchen at
+// capacity before it fails and is refunded, and how long to wait before
+// sending it again when the kitchen gives no Retry-After.
+func (s *Store) SetKitchenRetryPolicy(maxAttempts int, delay time.Duration) {
+	s.maxKitchenAttempts = maxAttempts
+	s.kitchenRetryDelay = delay
+}
+
 // HandleCreateOrder handles POST /order requests to create new pizza orders.
 // It validates the request, generates a UUID for the order, and stores it.
+// Orders with a delivery address are quoted by the delivery service first:
+// addresses outside every delivery zone are rejected with 422 Unprocessable
+// Entity, otherwise the zone's fee is added to the order's price, along with
+// the menu prices of the pizzas, taxes and the tip. A promo code that does not
+// apply to the order is rejected with 422 Unprocessable Entity. The order's
+// total is authorized with the payment provider before the order is sent to
+// the kitchen; declined payments are rejected with 402 Payment Required.
 func (s *Store) HandleCreateOrder(w http.ResponseWriter, r *http.Request) {
 	var req CreateOrderRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -74,65 +185,297 @@ func (s *Store) HandleCreateOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	order, err := s.placeOrder(r.Context(), req)
+	if err != nil {
+		writeOrderError(w, err)
+		return
+	}
+
+	// Return the created order
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(order)
+}
+
+// orderError is the reason an order could not be placed and the HTTP status
+// it is reported with.
+type orderError struct {
+	status  int
+	message string
+}
+
+// Error returns the message reported to the client.
+func (e *orderError) Error() string {
+	return e.message
+}
+
+// writeOrderError reports why an order could not be placed.
+func writeOrderError(w http.ResponseWriter, err error) {
+	var oerr *orderError
+	if errors.As(err, &oerr) {
+		http.Error(w, oerr.message, oerr.status)
+		return
+	}
+	http.Error(w, "Internal server error", http.StatusInternalServerError)
+}
+
+// paymentError maps a failed payment authorization to the response sent to
+// the customer.
+func paymentError(err error) error {
+	switch {
+	case errors.Is(err, ErrPaymentDeclined):
+		return &orderError{http.StatusPaymentRequired, "Payment declined"}
+	case errors.Is(err, ErrPaymentTimeout), errors.Is(err, context.DeadlineExceeded):
+		return &orderError{http.StatusGatewayTimeout, "Payment timed out"}
+	default:
+		return &orderError{http.StatusServiceUnavailable, "Payment service unavailable"}
+	}
+}
+
+// placeOrder validates, prices and pays for a new order, stores it and sends
+// it to the kitchen. It returns a copy of the order as it was stored, which
+// can be read while the kitchen and events update the order. Requests that
+// cannot be placed fail with an *orderError.
+func (s *Store) placeOrder(ctx context.Context, req CreateOrderRequest) (*Order, error) {
 	// Validate that at least one item is provided
 	if len(req.OrderItems) == 0 {
-		http.Error(w, "Order must contain at least one item", http.StatusBadRequest)
-		return
+		return nil, &orderError{http.StatusBadRequest, "Order must contain at least one item"}
+	}
+
+	if req.Priority == "" {
+		req.Priority = PriorityNormal
+	}
+	if !ValidPriority(req.Priority) {
+		return nil, &orderError{http.StatusBadRequest, "Invalid priority"}
+	}
+
+	if req.DeliveryAddress != nil && !req.DeliveryAddress.Valid() {
+		return nil, &orderError{http.StatusBadRequest, "Invalid delivery address"}
+	}
+
+	if req.Tip < 0 {
+		return nil, &orderError{http.StatusBadRequest, "Invalid tip"}
+	}
+
+	if req.ScheduledFor != nil && !validScheduleTime(*req.ScheduledFor, time.Now()) {
+		return nil, &orderError{http.StatusBadRequest, "Invalid scheduledFor"}
+	}
+
+	if req.CustomerID != nil {
+		if _, exists := s.GetCustomer(*req.CustomerID); !exists {
+			return nil, &orderError{http.StatusBadRequest, "Unknown customer"}
+		}
+	}
+
+	// Check the items against the menu before asking for a delivery quote
+	if err := s.validateItems(req.OrderItems); err != nil {
+		return nil, &orderError{http.StatusBadRequest, fmt.Sprintf("Invalid order items: %v", err)}
+	}
+
+	var quote DeliveryQuote
+	if req.DeliveryAddress != nil {
+		var err error
+		quote, err = s.quoteDelivery(ctx, *req.DeliveryAddress)
+		if errors.Is(err, ErrNotServiceable) {
+			return nil, &orderError{http.StatusUnprocessableEntity, "Delivery address is outside the delivery zones"}
+		}
+		if err != nil {
+			slog.Error("failed to quote delivery", "error", err)
+			return nil, &orderError{http.StatusServiceUnavailable, "Delivery service unavailable"}
+		}
+	}
+
+	var customerKey string
+	if req.CustomerID != nil {
+		customerKey = req.CustomerID.String()
+	}
+	var promo *Promotion
+	if req.PromoCode != "" {
+		var err error
+		promo, err = s.redeemPromotion(req.PromoCode, customerKey, req.OrderItems)
+		if err != nil {
+			return nil, &orderError{http.StatusUnprocessableEntity, fmt.Sprintf("Invalid promo code: %v", err)}
+		}
+	}
+
+	deliveryTime := s.deliveryEstimate
+	if req.DeliveryAddress != nil {
+		deliveryTime = time.Duration(quote.EstimatedSeconds) * time.Second
 	}
 
 	// Create new order with generated UUID
 	order := &Order{
-		OrderID:     uuid.New(),
-		OrderItems:  req.OrderItems,
-		OrderData:   req.OrderData,
-		OrderStatus: "pending",
+		OrderID:         uuid.New(),
+		CustomerID:      req.CustomerID,
+		OrderItems:      req.OrderItems,
+		OrderData:       req.OrderData,
+		OrderStatus:     "pending",
+		Priority:        req.Priority,
+		DeliveryAddress: req.DeliveryAddress,
+		DeliveryZone:    quote.Zone,
+		Pricing:         s.priceOrder(req.OrderItems, quote.Fee, req.Tip, promo),
+		CreatedAt:       time.Now(),
+		ScheduledFor:    req.ScheduledFor,
+	}
+	s.estimateOrder(ctx, order, deliveryTime)
+
+	// Authorize the payment before the kitchen starts on the order
+	if err := s.authorizePayment(ctx, order); err != nil {
+		if promo != nil {
+			s.releasePromotion(req.PromoCode, customerKey)
+		}
+		slog.Warn("payment authorization failed", "orderId", order.OrderID, "total", order.Pricing.Total, "error", err)
+		return nil, paymentError(err)
 	}
 
-	// Store the order
+	// Store the order, holding scheduled orders until it is time to cook them
 	s.mu.Lock()
 	s.orders[order.OrderID] = order
+	if order.ScheduledFor != nil {
+		order.OrderStatus = StatusScheduled
+		s.scheduleDispatch(order, s.cookEstimate+deliveryTime)
+	}
+	placed := order.snapshot()
 	s.mu.Unlock()
 
-	slog.Info("order created", "orderId", order.OrderID, "items", len(order.OrderItems))
+	slog.Info("order created", "orderId", placed.OrderID, "items", len(placed.OrderItems), "priority", placed.Priority, "total", placed.Pricing.Total)
 
 	// Call kitchen service to cook the order (background; detach from request context)
-	go s.callKitchenService(context.Background(), order)
+	if placed.ScheduledFor == nil {
+		go s.callKitchenService(context.Background(), order)
+	}
 
-	// Return the created order
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(order)
+	return placed, nil
+}
+
+// quoteDelivery asks the delivery service for the delivery zone and fee of an
+// address. It returns ErrNotServiceable if the address is outside every zone.
+func (s *Store) quoteDelivery(ctx context.Context, address Address) (DeliveryQuote, error) {
+	body, err := json.Marshal(QuoteRequest{DeliveryAddress: address})
+	if err != nil {
+		return DeliveryQuote{}, fmt.Errorf("marshaling quote request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.deliveryURL+"/delivery/quote", bytes.NewReader(body))
+	if err != nil {
+		return DeliveryQuote{}, fmt.Errorf("creating quote request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return DeliveryQuote{}, fmt.Errorf("calling delivery service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnprocessableEntity:
+		return DeliveryQuote{}, ErrNotServiceable
+	default:
+		return DeliveryQuote{}, fmt.Errorf("delivery service returned status %d", resp.StatusCode)
+	}
+
+	var quote DeliveryQuote
+	if err := json.NewDecoder(resp.Body).Decode(&quote); err != nil {
+		return DeliveryQuote{}, fmt.Errorf("decoding quote: %w", err)
+	}
+	return quote, nil
 }
 
-// callKitchenService sends a cook request to the kitchen service.
+// callKitchenService sends a cook request to the kitchen service. While the
+// kitchen is at capacity the order is sent again after its Retry-After
+// delay; once the kitchen attempts are used up the order fails and its
+// payment is refunded.
 func (s *Store) callKitchenService(ctx context.Context, order *Order) {
-	cookReq := CookRequest{
+	for attempt := 1; ; attempt++ {
+		cookReq := s.cookRequest(order)
+		retryAfter, err := s.sendCookRequest(ctx, cookReq)
+		if err == nil {
+			s.acceptedByKitchen(ctx, order, cookReq.OrderItems)
+			return
+		}
+		if !errors.Is(err, ErrKitchenAtCapacity) {
+			slog.Error("failed to call kitchen service", "orderId", order.OrderID, "error", err)
+			return
+		}
+
+		if attempt >= s.maxKitchenAttempts {
+			slog.Warn("kitchen at capacity, order failed", "orderId", order.OrderID, "attempts", attempt)
+			s.setStoreStatus(order.OrderID, "FAILED", "kitchen at capacity")
+			s.refundPayment(ctx, order.OrderID)
+			return
+		}
+		slog.Info("kitchen at capacity, retrying", "orderId", order.OrderID, "attempt", attempt, "retryAfter", retryAfter)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retryAfter):
+		}
+	}
+}
+
+// cookRequest returns the request that sends an order to the kitchen with
+// its current items, which can change until the kitchen has it.
+func (s *Store) cookRequest(order *Order) CookRequest {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return CookRequest{
 		OrderID:    order.OrderID,
 		OrderItems: order.OrderItems,
+		Priority:   order.Priority,
 	}
+}
 
+// acceptedByKitchen records that the kitchen took an order with the given
+// items. Items changed while the order was on its way are sent again.
+func (s *Store) acceptedByKitchen(ctx context.Context, order *Order, sent []OrderItem) {
+	s.mu.Lock()
+	order.inKitchen = true
+	current := order.OrderItems
+	s.mu.Unlock()
+
+	if slices.Equal(current, sent) {
+		return
+	}
+	if err := s.updateKitchenOrder(ctx, order.OrderID, current); err != nil {
+		slog.Error("failed to update kitchen order", "orderId", order.OrderID, "error", err)
+	}
+}
+
+// sendCookRequest sends an order to the kitchen once. It returns
+// ErrKitchenAtCapacity when the kitchen's queue is full, with the kitchen's
+// Retry-After delay, or the store's retry delay if it gave none.
+func (s *Store) sendCookRequest(ctx context.Context, cookReq CookRequest) (time.Duration, error) {
 	body, err := json.Marshal(cookReq)
 	if err != nil {
-		slog.Error("failed to marshal cook request", "orderId", order.OrderID, "error", err)
-		return
+		return 0, fmt.Errorf("marshaling cook request: %w", err)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.kitchenURL+"/cook", bytes.NewReader(body))
 	if err != nil {
-		slog.Error("failed to create kitchen request", "orderId", order.OrderID, "error", err)
-		return
+		return 0, fmt.Errorf("creating kitchen request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := s.httpClient.Do(req)
 	if err != nil {
-		slog.Error("failed to call kitchen service", "orderId", order.OrderID, "error", err)
-		return
+		return 0, fmt.Errorf("calling kitchen service: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusAccepted {
-		slog.Warn("kitchen service returned unexpected status", "orderId", order.OrderID, "status", resp.StatusCode)
+	switch resp.StatusCode {
+	case http.StatusAccepted:
+		return 0, nil
+	case http.StatusServiceUnavailable:
+		seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+		if err != nil || seconds < 0 {
+			return s.kitchenRetryDelay, ErrKitchenAtCapacity
+		}
+		return time.Duration(seconds) * time.Second, ErrKitchenAtCapacity
+	default:
+		return 0, fmt.Errorf("kitchen service returned status %d", resp.StatusCode)
 	}
 }
 
@@ -156,18 +499,44 @@ func (s *Store) UpdateOrderStatus(orderID uuid.UUID, status string) bool {
 	return true
 }
 
+// applyEventStatus updates the status of an order from a kitchen or delivery
+// event. Once the order is finished only another final status replaces it.
+// It reports whether the status was applied and whether the order exists.
+func (s *Store) applyEventStatus(orderID uuid.UUID, status string) (applied, exists bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	order, exists := s.orders[orderID]
+	if !exists {
+		return false, false
+	}
+	if finalStatus(order.OrderStatus) && !finalStatus(status) {
+		return false, true
+	}
+	order.OrderStatus = status
+	return true, true
+}
+
 // OrderEvent represents an event received from kitchen or delivery services.
+// Delivery progress events carry the driver and their location.
 type OrderEvent struct {
-	OrderID uuid.UUID `json:"orderId"`
-	Status  string    `json:"status"`
-	Source  string    `json:"source"` // "kitchen" or "delivery"
+	OrderID  uuid.UUID  `json:"orderId"`
+	Status   string     `json:"status"`
+	Source   string     `json:"source"` // "kitchen" or "delivery"
+	DriverID string     `json:"driverId,omitempty"`
+	Location *Location  `json:"location,omitempty"`
+	Reason   string     `json:"reason,omitempty"`   // why the kitchen or delivery failed
+	ProofID  *uuid.UUID `json:"proofId,omitempty"`  // proof of delivery of a DELIVERED event
+	Progress int        `json:"progress,omitempty"` // percentage of the order's cooking time
 }
 
 // HandleEvent handles POST /events requests to receive order updates
 // from kitchen and delivery services. It updates the order status and
 // broadcasts the update to all connected WebSocket clients.
 // When a kitchen DONE event is received (mapped to COOKED), it calls
-// the delivery service to deliver the order.
+// the delivery service to deliver the order; a DELIVERY_FAILED event
+// triggers the redelivery policy. The payment is captured once the order
+// is DELIVERED and refunded when the kitchen fails to cook it. Progress
+// events arriving after an order is finished are acknowledged and ignored.
 func (s *Store) HandleEvent(w http.ResponseWriter, r *http.Request) {
 	var event OrderEvent
 	if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
@@ -181,23 +550,47 @@ func (s *Store) HandleEvent(w http.ResponseWriter, r *http.Request) {
 		status = "COOKED"
 	}
 
-	// Update the order status
-	if !s.UpdateOrderStatus(event.OrderID, status) {
+	// Update the order status, unless a late progress event would revive a finished order
+	applied, exists := s.applyEventStatus(event.OrderID, status)
+	if !exists {
 		http.Error(w, "Order not found", http.StatusNotFound)
 		return
 	}
+	if !applied {
+		slog.Info("ignoring event for finished order", "orderId", event.OrderID, "status", status, "source", event.Source)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
 
-	// Track the event
+	// Track the event, the order's timeline, its ETA and the driver's latest location
+	now := time.Now()
 	s.trackEvent(event)
+	late := s.recordTimeline(event, status, now)
+	readyAt, deliveryAt := s.reviseETA(event, status, now)
+	if event.Location != nil {
+		s.trackLocation(event)
+	}
+	if status == "DELIVERED" && event.ProofID != nil {
+		s.attachProof(event.OrderID, *event.ProofID)
+	}
 
 	slog.Info("order event received", "orderId", event.OrderID, "status", status, "source", event.Source)
 
 	// Broadcast the update to WebSocket clients
 	s.BroadcastOrderUpdate(OrderUpdate{
-		OrderID: event.OrderID,
-		Status:  status,
-		Source:  event.Source,
+		OrderID:  event.OrderID,
+		Status:   status,
+		Source:   event.Source,
+		DriverID: event.DriverID,
+		Location: event.Location,
+		Reason:   event.Reason,
+
+		EstimatedReadyAt:    readyAt,
+		EstimatedDeliveryAt: deliveryAt,
 	})
+	if late != nil {
+		s.broadcastLate(late)
+	}
 
 	// If the order is cooked, call the delivery service
 	if status == "COOKED" {
@@ -207,14 +600,84 @@ func (s *Store) HandleEvent(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	switch status {
+	case "DELIVERED":
+		go s.capturePayment(context.Background(), event.OrderID)
+	case "FAILED":
+		go s.refundPayment(context.Background(), event.OrderID)
+	case StatusDeliveryFailed:
+		s.handleDeliveryFailure(event.OrderID, event.Reason)
+	}
+
 	w.WriteHeader(http.StatusOK)
 }
 
-// callDeliveryService sends a deliver request to the delivery service.
+// handleDeliveryFailure decides what happens to an order whose delivery
+// failed. Orders that used up their delivery attempts are refunded;
+// after a vehicle breakdown the order is reassigned to another driver right
+// away; otherwise a redelivery is scheduled after the redelivery delay.
+func (s *Store) handleDeliveryFailure(orderID uuid.UUID, reason string) {
+	s.mu.RLock()
+	order, exists := s.orders[orderID]
+	var attempts int
+	if exists {
+		attempts = order.DeliveryAttempts
+	}
+	s.mu.RUnlock()
+	if !exists {
+		return
+	}
+
+	switch {
+	case attempts >= s.maxDeliveryAttempts:
+		slog.Warn("delivery attempts exhausted, refund required", "orderId", orderID, "attempts", attempts, "reason", reason)
+		s.setStoreStatus(orderID, StatusRefundRequired, reason)
+		go s.refundPayment(context.Background(), orderID)
+	case reason == FailureVehicleBreakdown:
+		slog.Info("reassigning delivery", "orderId", orderID, "attempts", attempts)
+		go s.callDeliveryService(context.Background(), order)
+	default:
+		slog.Info("redelivery scheduled", "orderId", orderID, "attempts", attempts, "reason", reason, "delay", s.redeliveryDelay)
+		s.setStoreStatus(orderID, StatusRedeliveryScheduled, reason)
+		time.AfterFunc(s.redeliveryDelay, func() {
+			s.callDeliveryService(context.Background(), order)
+		})
+	}
+}
+
+// setStoreStatus updates the status of an order on the store's own account,
+// recording and broadcasting it as a store event.
+func (s *Store) setStoreStatus(orderID uuid.UUID, status, reason string) {
+	if !s.UpdateOrderStatus(orderID, status) {
+		return
+	}
+	s.recordStoreEvent(orderID, status, reason)
+}
+
+// recordStoreEvent records and broadcasts a status the store gave an order.
+func (s *Store) recordStoreEvent(orderID uuid.UUID, status, reason string) {
+	event := OrderEvent{OrderID: orderID, Status: status, Source: "store", Reason: reason}
+	s.trackEvent(event)
+	s.BroadcastOrderUpdate(OrderUpdate{
+		OrderID: orderID,
+		Status:  status,
+		Source:  event.Source,
+		Reason:  reason,
+	})
+}
+
+// callDeliveryService sends a deliver request to the delivery service and
+// counts it as a delivery attempt of the order.
 func (s *Store) callDeliveryService(ctx context.Context, order *Order) {
+	s.mu.Lock()
+	order.DeliveryAttempts++
+	s.mu.Unlock()
+
 	deliverReq := DeliverRequest{
-		OrderID:    order.OrderID,
-		OrderItems: order.OrderItems,
+		OrderID:         order.OrderID,
+		OrderItems:      order.OrderItems,
+		Priority:        order.Priority,
+		DeliveryAddress: order.DeliveryAddress,
 	}
 
 	body, err := json.Marshal(deliverReq)
@@ -249,25 +712,44 @@ func (s *Store) trackEvent(event OrderEvent) {
 	s.events[event.OrderID] = append(s.events[event.OrderID], event)
 }
 
-// GetOrderEvents retrieves all events for a given order ID.
-func (s *Store) GetOrderEvents(orderID uuid.UUID) []OrderEvent {
+// trackLocation records the location carried by a delivery event as the
+// order's latest location.
+func (s *Store) trackLocation(event OrderEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.locations[event.OrderID] = &OrderLocation{
+		OrderID:   event.OrderID,
+		DriverID:  event.DriverID,
+		Location:  *event.Location,
+		UpdatedAt: time.Now().UTC(),
+	}
+}
+
+// attachProof records a reference to the order's proof of delivery.
+func (s *Store) attachProof(orderID, proofID uuid.UUID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if order, exists := s.orders[orderID]; exists {
+		order.ProofOfDelivery = &ProofReference{
+			ProofID: proofID,
+			URL:     s.deliveryURL + "/deliver/" + orderID.String() + "/proof",
+		}
+	}
+}
+
+// GetOrderLocation retrieves the latest location of the driver delivering an order.
+func (s *Store) GetOrderLocation(orderID uuid.UUID) (*OrderLocation, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	return s.events[orderID]
+	location, exists := s.locations[orderID]
+	return location, exists
 }
 
-// HandleGetOrders handles GET /orders requests to retrieve all orders.
-func (s *Store) HandleGetOrders(w http.ResponseWriter, r *http.Request) {
+// GetOrderEvents retrieves all events for a given order ID.
+func (s *Store) GetOrderEvents(orderID uuid.UUID) []OrderEvent {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-
-	orders := make([]*Order, 0, len(s.orders))
-	for _, order := range s.orders {
-		orders = append(orders, order)
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(orders)
+	return s.events[orderID]
 }
 
 // HandleGetEvents handles GET /events requests to retrieve events for a specific order.
@@ -292,3 +774,32 @@ func (s *Store) HandleGetEvents(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(events)
 }
+
+// HandleGetOrderLocation handles GET /order/{orderId}/location requests.
+// Returns the latest location of the driver delivering the order, or 404 if
+// the order is unknown or no location has been reported for it yet.
+func (s *Store) HandleGetOrderLocation(w http.ResponseWriter, r *http.Request) {
+	orderID, err := uuid.Parse(chi.URLParam(r, "orderId"))
+	if err != nil {
+		http.Error(w, "Invalid orderId format", http.StatusBadRequest)
+		return
+	}
+
+	if _, exists := s.GetOrder(orderID); !exists {
+		http.Error(w, "Order not found", http.StatusNotFound)
+		return
+	}
+
+	location, exists := s.GetOrderLocation(orderID)
+	if !exists {
+		http.Error(w, "Location not available", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(location); err != nil {
+		slog.Error("failed to encode order location", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
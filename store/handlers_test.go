@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -201,6 +202,10 @@ func TestPostOrderCallsKitchenService(t *testing.T) {
 	var receivedRequest CookRequest
 	kitchenCalled := make(chan bool, 1)
 	kitchenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/cook" {
+			http.NotFound(w, r)
+			return
+		}
 		json.NewDecoder(r.Body).Decode(&receivedRequest)
 		w.WriteHeader(http.StatusAccepted)
 		json.NewEncoder(w).Encode(map[string]string{"status": "cooking"})
@@ -257,6 +262,85 @@ func TestPostOrderCallsKitchenService(t *testing.T) {
 	}
 }
 
+// kitchenAtCapacity creates a fake kitchen that turns cook requests away with 503 and a zero
+// Retry-After until it has refused the given number of them, and counts the requests.
+func kitchenAtCapacity(t *testing.T, refusals int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/cook" {
+			http.NotFound(w, r)
+			return
+		}
+		if int(requests.Add(1)) <= refusals {
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, "Kitchen is at capacity, try again later", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+// TestPostOrderRetriesKitchenAtCapacity verifies that an order turned away by a kitchen at
+// capacity is sent again after the kitchen's Retry-After delay until it is accepted.
+func TestPostOrderRetriesKitchenAtCapacity(t *testing.T) {
+	kitchenServer, requests := kitchenAtCapacity(t, 2)
+	store := NewStore()
+	store.SetKitchenURL(kitchenServer.URL)
+	router := chi.NewRouter()
+	router.Post("/order", store.HandleCreateOrder)
+
+	rec := sendJSON(router, http.MethodPost, "/order", CreateOrderRequest{OrderItems: []OrderItem{{PizzaType: "Margherita", Quantity: 1}}})
+	var order Order
+	json.NewDecoder(rec.Body).Decode(&order)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for requests.Load() < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the order to be sent 3 times, got %d", requests.Load())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if got := requests.Load(); got != 3 {
+		t.Errorf("expected no more requests once accepted, got %d", got)
+	}
+	got, _ := store.GetOrder(order.OrderID)
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	if got.OrderStatus != "pending" || got.Payment.Status != PaymentAuthorized {
+		t.Errorf("expected a pending order with its payment authorized, got '%s' and %+v", got.OrderStatus, got.Payment)
+	}
+}
+
+// TestPostOrderFailsWhenKitchenStaysAtCapacity verifies that an order the kitchen keeps turning
+// away fails once the kitchen attempts are used up and has its payment refunded.
+func TestPostOrderFailsWhenKitchenStaysAtCapacity(t *testing.T) {
+	kitchenServer, requests := kitchenAtCapacity(t, 100)
+	store := NewStore()
+	store.SetKitchenURL(kitchenServer.URL)
+	store.SetKitchenRetryPolicy(3, time.Millisecond)
+	router := chi.NewRouter()
+	router.Post("/order", store.HandleCreateOrder)
+
+	rec := sendJSON(router, http.MethodPost, "/order", CreateOrderRequest{OrderItems: []OrderItem{{PizzaType: "Margherita", Quantity: 1}}})
+	var order Order
+	json.NewDecoder(rec.Body).Decode(&order)
+
+	waitForPaymentStatus(t, store, order.OrderID, PaymentRefunded)
+	if got := requests.Load(); got != 3 {
+		t.Errorf("expected the order to be sent 3 times, got %d", got)
+	}
+	got, _ := store.GetOrder(order.OrderID)
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	if got.OrderStatus != "FAILED" {
+		t.Errorf("expected status 'FAILED', got '%s'", got.OrderStatus)
+	}
+}
+
 // TestEventsAreTrackedPerOrderID verifies that events are tracked per order ID.
 func TestEventsAreTrackedPerOrderID(t *testing.T) {
 	store := NewStore()
@@ -362,6 +446,34 @@ func TestDoneEventUpdatesStatusToCooked(t *testing.T) {
 	}
 }
 
+// TestLateProgressDoesNotReviveFinishedOrders verifies that progress events arriving after an
+// order failed or was delivered are ignored, while the final status stays.
+func TestLateProgressDoesNotReviveFinishedOrders(t *testing.T) {
+	store := NewStore()
+	router := chi.NewRouter()
+	router.Post("/events", store.HandleEvent)
+
+	for _, final := range []OrderEvent{
+		{Status: "FAILED", Source: "kitchen", Reason: "Margherita burnt in the oven"},
+		{Status: "DELIVERED", Source: "delivery"},
+	} {
+		order := &Order{OrderID: uuid.New(), OrderStatus: "pending"}
+		store.orders[order.OrderID] = order
+		final.OrderID = order.OrderID
+		postEvent(router, final)
+		postEvent(router, OrderEvent{OrderID: order.OrderID, Status: "baking 40%", Source: "kitchen", Progress: 40})
+		postEvent(router, OrderEvent{OrderID: order.OrderID, Status: "delivering 80%", Source: "delivery"})
+
+		got, _ := store.GetOrder(order.OrderID)
+		if got.OrderStatus != final.Status {
+			t.Errorf("expected the order to stay '%s', got '%s'", final.Status, got.OrderStatus)
+		}
+		if events := store.events[order.OrderID]; len(events) != 1 {
+			t.Errorf("expected only the '%s' event to be tracked, got %d events", final.Status, len(events))
+		}
+	}
+}
+
 // TestGetOrders verifies that GET /orders returns all orders.
 func TestGetOrders(t *testing.T) {
 	store := NewStore()
@@ -767,3 +879,486 @@ func TestDeliveryEventsAreTracked(t *testing.T) {
 		t.Errorf("expected final OrderStatus 'DELIVERED', got '%s'", order.OrderStatus)
 	}
 }
+
+// TestPostOrderDefaultsToNormalPriority verifies that orders without a priority are created as normal.
+func TestPostOrderDefaultsToNormalPriority(t *testing.T) {
+	store := NewStore()
+	router := chi.NewRouter()
+	router.Post("/order", store.HandleCreateOrder)
+
+	reqBody := CreateOrderRequest{
+		OrderItems: []OrderItem{{PizzaType: "Margherita", Quantity: 1}},
+	}
+	body, _ := json.Marshal(reqBody)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/order", bytes.NewReader(body)))
+
+	var response Order
+	json.Unmarshal(rec.Body.Bytes(), &response)
+	if response.Priority != PriorityNormal {
+		t.Errorf("expected priority '%s', got '%s'", PriorityNormal, response.Priority)
+	}
+}
+
+// TestPostOrderInvalidPriority verifies that POST /order returns 400 for unknown priorities.
+func TestPostOrderInvalidPriority(t *testing.T) {
+	store := NewStore()
+	router := chi.NewRouter()
+	router.Post("/order", store.HandleCreateOrder)
+
+	reqBody := CreateOrderRequest{
+		OrderItems: []OrderItem{{PizzaType: "Margherita", Quantity: 1}},
+		Priority:   "urgent",
+	}
+	body, _ := json.Marshal(reqBody)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/order", bytes.NewReader(body)))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 Bad Request, got %d", rec.Code)
+	}
+}
+
+// TestPriorityIsPassedToKitchenAndDelivery verifies that the order priority is carried
+// through the cook and deliver requests.
+func TestPriorityIsPassedToKitchenAndDelivery(t *testing.T) {
+	cookRequests := make(chan CookRequest, 1)
+	kitchenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/cook" {
+			http.NotFound(w, r)
+			return
+		}
+		var req CookRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		w.WriteHeader(http.StatusAccepted)
+		cookRequests <- req
+	}))
+	defer kitchenServer.Close()
+
+	deliverRequests := make(chan DeliverRequest, 1)
+	deliveryServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req DeliverRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		w.WriteHeader(http.StatusAccepted)
+		deliverRequests <- req
+	}))
+	defer deliveryServer.Close()
+
+	store := NewStore()
+	store.SetKitchenURL(kitchenServer.URL)
+	store.SetDeliveryURL(deliveryServer.URL)
+
+	router := chi.NewRouter()
+	router.Post("/order", store.HandleCreateOrder)
+	router.Post("/events", store.HandleEvent)
+
+	reqBody := CreateOrderRequest{
+		OrderItems: []OrderItem{{PizzaType: "Margherita", Quantity: 1}},
+		Priority:   PriorityExpress,
+	}
+	body, _ := json.Marshal(reqBody)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/order", bytes.NewReader(body)))
+
+	var createdOrder Order
+	json.Unmarshal(rec.Body.Bytes(), &createdOrder)
+
+	select {
+	case req := <-cookRequests:
+		if req.Priority != PriorityExpress {
+			t.Errorf("expected kitchen to receive priority '%s', got '%s'", PriorityExpress, req.Priority)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for kitchen service to be called")
+	}
+
+	eventBody, _ := json.Marshal(OrderEvent{OrderID: createdOrder.OrderID, Status: "DONE", Source: "kitchen"})
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/events", bytes.NewReader(eventBody)))
+
+	select {
+	case req := <-deliverRequests:
+		if req.Priority != PriorityExpress {
+			t.Errorf("expected delivery to receive priority '%s', got '%s'", PriorityExpress, req.Priority)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for delivery service to be called")
+	}
+}
+
+// TestPostOrderInvalidDeliveryAddress verifies that POST /order returns 400 for addresses without a street or valid coordinates.
+func TestPostOrderInvalidDeliveryAddress(t *testing.T) {
+	store := NewStore()
+	router := chi.NewRouter()
+	router.Post("/order", store.HandleCreateOrder)
+
+	for _, address := range []Address{
+		{Lat: 41.39, Lon: 2.17},
+		{Street: "Carrer de Mallorca 401", Lat: 141.39, Lon: 2.17},
+	} {
+		reqBody := CreateOrderRequest{
+			OrderItems:      []OrderItem{{PizzaType: "Margherita", Quantity: 1}},
+			DeliveryAddress: &address,
+		}
+		body, _ := json.Marshal(reqBody)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/order", bytes.NewReader(body)))
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400 Bad Request for %+v, got %d", address, rec.Code)
+		}
+	}
+}
+
+// TestDeliveryAddressIsPassedToDelivery verifies that the delivery address is stored on the
+// order and carried through the deliver request.
+func TestDeliveryAddressIsPassedToDelivery(t *testing.T) {
+	kitchenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer kitchenServer.Close()
+
+	deliverRequests := make(chan DeliverRequest, 1)
+	deliveryServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/delivery/quote" {
+			json.NewEncoder(w).Encode(DeliveryQuote{Zone: "center", Fee: 199, MaxDeliveryTime: 12, EstimatedSeconds: 5})
+			return
+		}
+		var req DeliverRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		w.WriteHeader(http.StatusAccepted)
+		deliverRequests <- req
+	}))
+	defer deliveryServer.Close()
+
+	store := NewStore()
+	store.SetKitchenURL(kitchenServer.URL)
+	store.SetDeliveryURL(deliveryServer.URL)
+
+	router := chi.NewRouter()
+	router.Post("/order", store.HandleCreateOrder)
+	router.Post("/events", store.HandleEvent)
+
+	address := Address{Street: "Carrer de Mallorca 401", City: "Barcelona", PostalCode: "08013", Lat: 41.4036, Lon: 2.1744}
+	reqBody := CreateOrderRequest{
+		OrderItems:      []OrderItem{{PizzaType: "Margherita", Quantity: 1}},
+		DeliveryAddress: &address,
+	}
+	body, _ := json.Marshal(reqBody)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/order", bytes.NewReader(body)))
+
+	var createdOrder Order
+	json.Unmarshal(rec.Body.Bytes(), &createdOrder)
+	if createdOrder.DeliveryAddress == nil || *createdOrder.DeliveryAddress != address {
+		t.Errorf("expected order to have delivery address %+v, got %+v", address, createdOrder.DeliveryAddress)
+	}
+
+	eventBody, _ := json.Marshal(OrderEvent{OrderID: createdOrder.OrderID, Status: "DONE", Source: "kitchen"})
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/events", bytes.NewReader(eventBody)))
+
+	select {
+	case req := <-deliverRequests:
+		if req.DeliveryAddress == nil || *req.DeliveryAddress != address {
+			t.Errorf("expected delivery to receive address %+v, got %+v", address, req.DeliveryAddress)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for delivery service to be called")
+	}
+}
+
+// TestGetOrderLocationReturnsLatestLocation verifies that GET /order/{orderId}/location returns
+// the location carried by the latest delivery event.
+func TestGetOrderLocationReturnsLatestLocation(t *testing.T) {
+	store := NewStore()
+	router := chi.NewRouter()
+	router.Post("/events", store.HandleEvent)
+	router.Get("/order/{orderId}/location", store.HandleGetOrderLocation)
+
+	order := &Order{OrderID: uuid.New(), OrderItems: []OrderItem{{PizzaType: "Margherita", Quantity: 1}}, OrderStatus: "COOKED"}
+	store.orders[order.OrderID] = order
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/order/"+order.OrderID.String()+"/location", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 before any location, got %d", rec.Code)
+	}
+
+	for _, location := range []Location{
+		{Lat: 41.3900, Lon: 2.1686, Heading: 0, ETASeconds: 4},
+		{Lat: 41.3950, Lon: 2.1686, Heading: 0, ETASeconds: 3},
+	} {
+		event := OrderEvent{OrderID: order.OrderID, Status: "delivering 40%", Source: "delivery", DriverID: "driver-1", Location: &location}
+		body, _ := json.Marshal(event)
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/events", bytes.NewReader(body)))
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/order/"+order.OrderID.String()+"/location", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200 OK, got %d", rec.Code)
+	}
+
+	var got OrderLocation
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if got.OrderID != order.OrderID || got.DriverID != "driver-1" {
+		t.Errorf("expected location of %s by driver-1, got %s by '%s'", order.OrderID, got.OrderID, got.DriverID)
+	}
+	if got.Location.Lat != 41.3950 || got.Location.ETASeconds != 3 {
+		t.Errorf("expected the latest location, got %+v", got.Location)
+	}
+}
+
+// TestGetOrderLocationUnknownOrder verifies that GET /order/{orderId}/location returns 404 for
+// unknown orders and 400 for invalid IDs.
+func TestGetOrderLocationUnknownOrder(t *testing.T) {
+	store := NewStore()
+	router := chi.NewRouter()
+	router.Get("/order/{orderId}/location", store.HandleGetOrderLocation)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/order/"+uuid.New().String()+"/location", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 Not Found, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/order/not-a-uuid/location", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 Bad Request, got %d", rec.Code)
+	}
+}
+
+// deliveryFailureSetup creates a store whose fake delivery service reports every deliver
+// request on the returned channel, and an order that has been cooked and handed to delivery.
+func deliveryFailureSetup(t *testing.T, maxAttempts int) (*Store, *chi.Mux, *Order, chan DeliverRequest) {
+	t.Helper()
+	deliverRequests := make(chan DeliverRequest, 10)
+	deliveryServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req DeliverRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		w.WriteHeader(http.StatusAccepted)
+		deliverRequests <- req
+	}))
+	t.Cleanup(deliveryServer.Close)
+
+	store := NewStore()
+	store.SetDeliveryURL(deliveryServer.URL)
+	store.SetRedeliveryPolicy(maxAttempts, 200*time.Millisecond)
+
+	router := chi.NewRouter()
+	router.Post("/events", store.HandleEvent)
+
+	order := &Order{OrderID: uuid.New(), OrderItems: []OrderItem{{PizzaType: "Margherita", Quantity: 1}}, OrderStatus: "cooking"}
+	store.orders[order.OrderID] = order
+
+	postEvent(router, OrderEvent{OrderID: order.OrderID, Status: "DONE", Source: "kitchen"})
+	select {
+	case <-deliverRequests:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for the first delivery attempt")
+	}
+	return store, router, order, deliverRequests
+}
+
+// postEvent sends an event to the store's /events endpoint.
+func postEvent(router *chi.Mux, event OrderEvent) {
+	body, _ := json.Marshal(event)
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/events", bytes.NewReader(body)))
+}
+
+// TestDeliveryFailureSchedulesRedelivery verifies that an order whose customer was not home is
+// delivered again after the redelivery delay.
+func TestDeliveryFailureSchedulesRedelivery(t *testing.T) {
+	store, router, order, deliverRequests := deliveryFailureSetup(t, 3)
+
+	start := time.Now()
+	postEvent(router, OrderEvent{OrderID: order.OrderID, Status: StatusDeliveryFailed, Source: "delivery", Reason: FailureCustomerNotHome})
+
+	got, _ := store.GetOrder(order.OrderID)
+	store.mu.RLock()
+	status := got.OrderStatus
+	store.mu.RUnlock()
+	if status != StatusRedeliveryScheduled {
+		t.Errorf("expected status '%s', got '%s'", StatusRedeliveryScheduled, status)
+	}
+
+	select {
+	case <-deliverRequests:
+		if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+			t.Errorf("expected redelivery after the delay, got it after %v", elapsed)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for redelivery")
+	}
+
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	if got.DeliveryAttempts != 2 {
+		t.Errorf("expected 2 delivery attempts, got %d", got.DeliveryAttempts)
+	}
+}
+
+// TestDeliveryFailureReassignsAfterBreakdown verifies that an order is handed back to delivery
+// right away when the driver's vehicle broke down.
+func TestDeliveryFailureReassignsAfterBreakdown(t *testing.T) {
+	_, router, order, deliverRequests := deliveryFailureSetup(t, 3)
+
+	postEvent(router, OrderEvent{OrderID: order.OrderID, Status: StatusDeliveryFailed, Source: "delivery", Reason: FailureVehicleBreakdown})
+
+	select {
+	case req := <-deliverRequests:
+		if req.OrderID != order.OrderID {
+			t.Errorf("expected order %s to be reassigned, got %s", order.OrderID, req.OrderID)
+		}
+	case <-time.After(150 * time.Millisecond):
+		t.Fatal("expected the order to be reassigned without waiting for the redelivery delay")
+	}
+}
+
+// TestDeliveryFailureRequiresRefundAfterMaxAttempts verifies that an order is marked for refund
+// once it has used up its delivery attempts.
+func TestDeliveryFailureRequiresRefundAfterMaxAttempts(t *testing.T) {
+	store, router, order, deliverRequests := deliveryFailureSetup(t, 1)
+
+	postEvent(router, OrderEvent{OrderID: order.OrderID, Status: StatusDeliveryFailed, Source: "delivery", Reason: FailureAddressNotFound})
+
+	select {
+	case <-deliverRequests:
+		t.Fatal("expected no further delivery attempt")
+	case <-time.After(400 * time.Millisecond):
+	}
+
+	got, _ := store.GetOrder(order.OrderID)
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	if got.OrderStatus != StatusRefundRequired {
+		t.Errorf("expected status '%s', got '%s'", StatusRefundRequired, got.OrderStatus)
+	}
+	events := store.events[order.OrderID]
+	if last := events[len(events)-1]; last.Source != "store" || last.Reason != FailureAddressNotFound {
+		t.Errorf("expected a store event with the failure reason, got %+v", last)
+	}
+}
+
+// TestDeliveredEventAttachesProofOfDelivery verifies that the store references the proof of
+// delivery carried by a DELIVERED event on the order.
+func TestDeliveredEventAttachesProofOfDelivery(t *testing.T) {
+	store := NewStore()
+	store.SetDeliveryURL("http://delivery:8082")
+	router := chi.NewRouter()
+	router.Post("/events", store.HandleEvent)
+	router.Get("/orders", store.HandleGetOrders)
+
+	order := &Order{OrderID: uuid.New(), OrderItems: []OrderItem{{PizzaType: "Margherita", Quantity: 1}}, OrderStatus: "delivering 80%"}
+	store.orders[order.OrderID] = order
+
+	proofID := uuid.New()
+	postEvent(router, OrderEvent{OrderID: order.OrderID, Status: "DELIVERED", Source: "delivery", DriverID: "driver-1", ProofID: &proofID})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/orders", nil))
+	var orders []Order
+	json.NewDecoder(rec.Body).Decode(&orders)
+	if len(orders) != 1 || orders[0].ProofOfDelivery == nil {
+		t.Fatalf("expected the order to reference its proof of delivery, got %+v", orders)
+	}
+	ref := orders[0].ProofOfDelivery
+	if ref.ProofID != proofID {
+		t.Errorf("expected proof %s, got %s", proofID, ref.ProofID)
+	}
+	if want := "http://delivery:8082/deliver/" + order.OrderID.String() + "/proof"; ref.URL != want {
+		t.Errorf("expected URL '%s', got '%s'", want, ref.URL)
+	}
+}
+
+// quoteServer returns a fake delivery service that answers quote requests with the given
+// status and quote, and accepts deliver requests.
+func quoteServer(t *testing.T, status int, quote DeliveryQuote) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/delivery/quote" {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		var req QuoteRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || !req.DeliveryAddress.Valid() {
+			t.Errorf("expected a quote request with a valid address, got %+v", req)
+		}
+		if status != http.StatusOK {
+			http.Error(w, "Address is outside the delivery zones", status)
+			return
+		}
+		json.NewEncoder(w).Encode(quote)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// postOrderWithAddress posts an order with a delivery address to the store.
+func postOrderWithAddress(store *Store) *httptest.ResponseRecorder {
+	router := chi.NewRouter()
+	router.Post("/order", store.HandleCreateOrder)
+
+	reqBody := CreateOrderRequest{
+		OrderItems:      []OrderItem{{PizzaType: "Margherita", Quantity: 1}},
+		DeliveryAddress: &Address{Street: "Carrer de Mallorca 401", Lat: 41.4036, Lon: 2.1744},
+	}
+	body, _ := json.Marshal(reqBody)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/order", bytes.NewReader(body)))
+	return rec
+}
+
+// TestPostOrderAddsDeliveryZoneFee verifies that orders with an address get the quoted zone and
+// its fee added to the total.
+func TestPostOrderAddsDeliveryZoneFee(t *testing.T) {
+	server := quoteServer(t, http.StatusOK, DeliveryQuote{Zone: "city", Fee: 299, MaxDeliveryTime: 20, EstimatedSeconds: 8})
+	store := NewStore()
+	store.SetDeliveryURL(server.URL)
+
+	rec := postOrderWithAddress(store)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status 201 Created, got %d", rec.Code)
+	}
+
+	var order Order
+	json.NewDecoder(rec.Body).Decode(&order)
+	if order.DeliveryZone != "city" || order.Pricing.DeliveryFee != 299 {
+		t.Errorf("expected zone 'city' with fee 299, got '%s' with %d", order.DeliveryZone, order.Pricing.DeliveryFee)
+	}
+	// Margherita 1000 + fee 299 + 8% sales tax on 1299 (103.92, rounded to 104)
+	if order.Pricing.Total != 1403 {
+		t.Errorf("expected total 1403, got %d", order.Pricing.Total)
+	}
+}
+
+// TestPostOrderOutsideDeliveryZones verifies that orders to addresses outside every delivery zone
+// are rejected with 422 and not stored.
+func TestPostOrderOutsideDeliveryZones(t *testing.T) {
+	server := quoteServer(t, http.StatusUnprocessableEntity, DeliveryQuote{})
+	store := NewStore()
+	store.SetDeliveryURL(server.URL)
+
+	rec := postOrderWithAddress(store)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected status 422 Unprocessable Entity, got %d", rec.Code)
+	}
+	if len(store.orders) != 0 {
+		t.Errorf("expected no order to be stored, got %d", len(store.orders))
+	}
+}
+
+// TestPostOrderDeliveryServiceUnavailable verifies that orders with an address are rejected with
+// 503 when the delivery service cannot quote them.
+func TestPostOrderDeliveryServiceUnavailable(t *testing.T) {
+	server := quoteServer(t, http.StatusInternalServerError, DeliveryQuote{})
+	store := NewStore()
+	store.SetDeliveryURL(server.URL)
+
+	rec := postOrderWithAddress(store)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503 Service Unavailable, got %d", rec.Code)
+	}
+}
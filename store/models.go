@@ -3,7 +3,11 @@
 // and sends real-time updates to frontend clients via WebSocket.
 package store
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 // OrderItem represents a single item in an order, containing the pizza type
 // and the quantity requested.
@@ -12,11 +16,139 @@ type OrderItem struct {
 	Quantity  int    `json:"quantity"`
 }
 
-// Order represents a pizza order with a unique identifier, items, additional data,
-// and current status.
+// Order priority constants. Higher priority orders are cooked and delivered
+// first; waiting orders slowly gain priority so none are starved.
+const (
+	PriorityNormal  = "normal"
+	PriorityExpress = "express"
+	PriorityVIP     = "vip"
+)
+
+// ValidPriority reports whether the given priority is known.
+func ValidPriority(priority string) bool {
+	switch priority {
+	case PriorityNormal, PriorityExpress, PriorityVIP:
+		return true
+	}
+	return false
+}
+
+// Address is where an order is delivered. Lat and Lon are the customer's
+// coordinates in decimal degrees, used by the delivery service to estimate
+// the delivery time, and Recipient is the person the order is handed to.
+type Address struct {
+	Recipient  string  `json:"recipient,omitempty"`
+	Street     string  `json:"street"`
+	City       string  `json:"city,omitempty"`
+	PostalCode string  `json:"postalCode,omitempty"`
+	Lat        float64 `json:"lat"`
+	Lon        float64 `json:"lon"`
+}
+
+// Valid reports whether the address has a street and coordinates on the map.
+func (a Address) Valid() bool {
+	return a.Street != "" && a.Lat >= -90 && a.Lat <= 90 && a.Lon >= -180 && a.Lon <= 180
+}
+
+// Order statuses set by the store when a delivery fails.
+const (
+	StatusDeliveryFailed      = "DELIVERY_FAILED"
+	StatusRedeliveryScheduled = "REDELIVERY_SCHEDULED"
+	StatusRefundRequired      = "REFUND_REQUIRED"
+)
+
+// Order statuses of orders scheduled for later.
+const (
+	StatusScheduled = "SCHEDULED"
+	StatusCancelled = "CANCELLED"
+)
+
+// finalStatus reports whether an order in the status is finished, after
+// which progress reported late by the kitchen or delivery no longer changes
+// it.
+func finalStatus(status string) bool {
+	switch status {
+	case "DELIVERED", "FAILED", StatusRefundRequired, StatusCancelled:
+		return true
+	}
+	return false
+}
+
+// Delivery failure reasons reported by the delivery service.
+const (
+	FailureCustomerNotHome  = "customer_not_home"
+	FailureAddressNotFound  = "address_not_found"
+	FailureVehicleBreakdown = "vehicle_breakdown"
+)
+
+// ProofReference points to the proof of delivery kept by the delivery service.
+type ProofReference struct {
+	ProofID uuid.UUID `json:"proofId"`
+	URL     string    `json:"url"`
+}
+
+// Order represents a pizza order with a unique identifier, the customer who
+// placed it (if known), items, additional data, priority, delivery address,
+// current status and creation time. DeliveryAttempts counts the times the
+// order was handed to the delivery service, and ProofOfDelivery is set once
+// the order is delivered. Pricing is the itemized price of the order,
+// including the delivery fee of its delivery zone, and Payment is the payment
+// authorized for it. Orders scheduled for later are held until DispatchAt,
+// when they are sent to the kitchen. Timeline records when the order reached each stage, and
+// Late is set once it exceeds the SLA of any of its LateStages. EstimatedReadyAt
+// and EstimatedDeliveryAt are revised as kitchen and delivery events arrive.
 type Order struct {
-	OrderID     uuid.UUID   `json:"orderId"`
-	OrderItems  []OrderItem `json:"orderItems"`
-	OrderData   string      `json:"orderData"`
-	OrderStatus string      `json:"orderStatus"`
+	OrderID          uuid.UUID       `json:"orderId"`
+	CustomerID       *uuid.UUID      `json:"customerId,omitempty"`
+	OrderItems       []OrderItem     `json:"orderItems"`
+	OrderData        string          `json:"orderData"`
+	OrderStatus      string          `json:"orderStatus"`
+	Priority         string          `json:"priority"`
+	DeliveryAddress  *Address        `json:"deliveryAddress,omitempty"`
+	DeliveryAttempts int             `json:"deliveryAttempts"`
+	ProofOfDelivery  *ProofReference `json:"proofOfDelivery,omitempty"`
+	DeliveryZone     string          `json:"deliveryZone,omitempty"`
+	Pricing          Pricing         `json:"pricing"`
+	Payment          *Payment        `json:"payment,omitempty"`
+	CreatedAt        time.Time       `json:"createdAt"`
+	ScheduledFor     *time.Time      `json:"scheduledFor,omitempty"`
+	DispatchAt       *time.Time      `json:"dispatchAt,omitempty"`
+	Timeline         OrderTimeline   `json:"timeline"`
+	Late             bool            `json:"late"`
+	LateStages       []string        `json:"lateStages,omitempty"`
+
+	EstimatedReadyAt    *time.Time    `json:"estimatedReadyAt,omitempty"`
+	EstimatedDeliveryAt *time.Time    `json:"estimatedDeliveryAt,omitempty"`
+	cookTime            time.Duration // expected cooking time, used to revise the estimates
+	deliveryTime        time.Duration // expected delivery time, used to revise the estimates
+	inKitchen           bool          // the kitchen accepted the order
+}
+
+// snapshot returns a copy of the order that can still be read once s.mu is
+// released. The caller must hold s.mu.
+func (o *Order) snapshot() *Order {
+	c := *o
+	if o.Payment != nil {
+		payment := *o.Payment
+		c.Payment = &payment
+	}
+	return &c
+}
+
+// Location is the simulated GPS position of the driver delivering an order.
+// Heading is the direction of travel in degrees clockwise from north, and
+// ETASeconds the time left until the driver reaches the order's address.
+type Location struct {
+	Lat        float64 `json:"lat"`
+	Lon        float64 `json:"lon"`
+	Heading    float64 `json:"heading"`
+	ETASeconds int     `json:"etaSeconds"`
+}
+
+// OrderLocation is the latest known location of the driver delivering an order.
+type OrderLocation struct {
+	OrderID   uuid.UUID `json:"orderId"`
+	DriverID  string    `json:"driverId,omitempty"`
+	Location  Location  `json:"location"`
+	UpdatedAt time.Time `json:"updatedAt"`
 }
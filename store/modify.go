@@ -0,0 +1,246 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// StatusModified is broadcast to WebSocket clients when the items of an order
+// are changed. It does not change the order's status.
+const StatusModified = "MODIFIED"
+
+// ErrCookingStarted is returned by the kitchen when an order can no longer be
+// changed because a cook station has started on it.
+var ErrCookingStarted = errors.New("order has already started cooking")
+
+// ErrNotInKitchen is returned by the kitchen for a pending order it has not
+// received yet, while the store is still sending it or waiting for room in a
+// kitchen at capacity.
+var ErrNotInKitchen = errors.New("order is not in the kitchen yet")
+
+// UpdateOrderRequest represents the request body for changing the items of an
+// order. The items replace those of the order.
+type UpdateOrderRequest struct {
+	OrderItems []OrderItem `json:"orderItems"`
+}
+
+// modifiable reports whether the items of an order can still be changed: it
+// is scheduled, or pending and the kitchen has not started cooking it. The
+// caller must hold s.mu.
+func modifiable(order *Order) bool {
+	switch order.OrderStatus {
+	case StatusScheduled:
+		return true
+	case "pending":
+		return order.Timeline.CookingStartedAt == nil
+	}
+	return false
+}
+
+// HandleUpdateOrder handles PATCH /order/{orderId} requests to add or remove
+// items of an order or change their quantities before it is cooked. The order
+// is priced again and, if its total changed, a new payment is authorized in
+// place of the old one. Orders already in the kitchen are updated there too.
+// Returns 409 Conflict once cooking has started.
+func (s *Store) HandleUpdateOrder(w http.ResponseWriter, r *http.Request) {
+	orderID, err := uuid.Parse(chi.URLParam(r, "orderId"))
+	if err != nil {
+		http.Error(w, "Invalid orderId format", http.StatusBadRequest)
+		return
+	}
+
+	var req UpdateOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	order, err := s.modifyOrder(r.Context(), orderID, req.OrderItems)
+	if err != nil {
+		writeOrderError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(order); err != nil {
+		slog.Error("failed to encode order", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// modifyOrder replaces the items of an order that has not started cooking and
+// returns a copy of the modified order. The new total is authorized and the
+// kitchen has accepted the change before the order is updated, so a rejected
+// change leaves the order as it was.
+func (s *Store) modifyOrder(ctx context.Context, orderID uuid.UUID, items []OrderItem) (*Order, error) {
+	if len(items) == 0 {
+		return nil, &orderError{http.StatusBadRequest, "Order must contain at least one item"}
+	}
+	if err := s.validateItems(items); err != nil {
+		return nil, &orderError{http.StatusBadRequest, fmt.Sprintf("Invalid order items: %v", err)}
+	}
+
+	s.mu.RLock()
+	order, exists := s.orders[orderID]
+	if !exists {
+		s.mu.RUnlock()
+		return nil, &orderError{http.StatusNotFound, "Order not found"}
+	}
+	if !modifiable(order) {
+		s.mu.RUnlock()
+		return nil, &orderError{http.StatusConflict, "Order can no longer be modified"}
+	}
+	scheduled := order.OrderStatus == StatusScheduled
+	current := order.Pricing
+	var payment *Payment
+	if order.Payment != nil {
+		paid := *order.Payment
+		payment = &paid
+	}
+	s.mu.RUnlock()
+
+	pricing, err := s.repriceOrder(current, items)
+	if err != nil {
+		return nil, &orderError{http.StatusUnprocessableEntity, fmt.Sprintf("Invalid promo code: %v", err)}
+	}
+
+	// Authorize the new total before anything changes
+	replaced := payment
+	if payment == nil || payment.Amount != pricing.Total {
+		draft := &Order{OrderID: orderID, Pricing: pricing}
+		if err := s.authorizePayment(ctx, draft); err != nil {
+			slog.Warn("payment authorization failed", "orderId", orderID, "total", pricing.Total, "error", err)
+			return nil, paymentError(err)
+		}
+		payment = draft.Payment
+	}
+	authorized := payment != replaced
+	discard := func() {
+		if authorized {
+			go s.voidPayment(context.Background(), orderID, payment.ID)
+		}
+	}
+
+	// An order the kitchen does not have yet is sent with its new items
+	notInKitchen := false
+	if !scheduled {
+		err := s.updateKitchenOrder(ctx, orderID, items)
+		if errors.Is(err, ErrNotInKitchen) {
+			notInKitchen = true
+			err = nil
+		}
+		if errors.Is(err, ErrCookingStarted) {
+			discard()
+			return nil, &orderError{http.StatusConflict, "Order can no longer be modified"}
+		}
+		if err != nil {
+			discard()
+			slog.Error("failed to update kitchen order", "orderId", orderID, "error", err)
+			return nil, &orderError{http.StatusServiceUnavailable, "Kitchen service unavailable"}
+		}
+	}
+
+	s.mu.Lock()
+	// A scheduled order sent to the kitchen in the meantime is cooked as it was
+	if scheduled && order.OrderStatus != StatusScheduled {
+		s.mu.Unlock()
+		discard()
+		return nil, &orderError{http.StatusConflict, "Order can no longer be modified"}
+	}
+	// So is an order that failed while waiting for room in the kitchen
+	if notInKitchen && order.OrderStatus != "pending" {
+		s.mu.Unlock()
+		discard()
+		return nil, &orderError{http.StatusConflict, "Order can no longer be modified"}
+	}
+	order.OrderItems = items
+	order.Pricing = pricing
+	if authorized {
+		order.Payment = payment
+	}
+	// The kitchen took the order with its old items since it answered
+	resend := notInKitchen && order.inKitchen
+	modified := order.snapshot()
+	s.mu.Unlock()
+
+	if resend {
+		if err := s.updateKitchenOrder(ctx, orderID, items); err != nil {
+			slog.Error("failed to update kitchen order", "orderId", orderID, "error", err)
+		}
+	}
+
+	if authorized && replaced != nil {
+		go s.voidPayment(context.Background(), orderID, replaced.ID)
+	}
+
+	slog.Info("order modified", "orderId", orderID, "items", len(items), "total", pricing.Total)
+	s.recordStoreEvent(orderID, StatusModified, fmt.Sprintf("%d item(s), total %d", len(items), pricing.Total))
+	return modified, nil
+}
+
+// repriceOrder prices new items for an order with its current pricing,
+// keeping its delivery fee, tip and promo code. It returns an error if the
+// promo code does not apply to the new items.
+func (s *Store) repriceOrder(current Pricing, items []OrderItem) (Pricing, error) {
+	if current.PromoCode == "" {
+		return s.priceOrder(items, current.DeliveryFee, current.Tip, nil), nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	promo, exists := s.promotions[normalizePromoCode(current.PromoCode)]
+	if !exists {
+		return Pricing{}, ErrPromoNotFound
+	}
+	pricing := s.priceOrder(items, current.DeliveryFee, current.Tip, promo)
+	if pricing.Subtotal < promo.MinOrderAmount {
+		return Pricing{}, ErrPromoMinimumNotMet
+	}
+	if pricing.Discount == 0 {
+		return Pricing{}, ErrPromoNotApplicable
+	}
+	return pricing, nil
+}
+
+// updateKitchenOrder sends the new items of an order to the kitchen. It
+// returns ErrCookingStarted if the kitchen has started cooking the order, and
+// ErrNotInKitchen if the kitchen does not have the order yet.
+func (s *Store) updateKitchenOrder(ctx context.Context, orderID uuid.UUID, items []OrderItem) error {
+	body, err := json.Marshal(CookRequest{OrderID: orderID, OrderItems: items})
+	if err != nil {
+		return fmt.Errorf("marshaling cook request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.kitchenURL+"/cook/"+orderID.String(), bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating kitchen request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("calling kitchen service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusConflict:
+		return ErrCookingStarted
+	case http.StatusNotFound:
+		return ErrNotInKitchen
+	default:
+		return fmt.Errorf("kitchen service returned status %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,278 @@
+package store
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// fakeKitchen accepts cook requests and records the updates sent for queued orders, rejecting
+// them once started is set. While full is set it is at capacity: cook requests are turned away
+// and updates answered as for orders it does not have.
+type fakeKitchen struct {
+	mu       sync.Mutex
+	accepted []CookRequest
+	updates  []CookRequest
+	started  bool
+	full     bool
+}
+
+// ServeHTTP accepts POST /cook and answers PUT /cook/{orderId} like the kitchen service.
+func (k *fakeKitchen) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if r.Method != http.MethodPut {
+		if k.full {
+			http.Error(w, "Kitchen is at capacity, try again later", http.StatusServiceUnavailable)
+			return
+		}
+		var req CookRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		k.accepted = append(k.accepted, req)
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	if k.full {
+		http.Error(w, "Order not found", http.StatusNotFound)
+		return
+	}
+	if k.started {
+		http.Error(w, "Order has already started cooking", http.StatusConflict)
+		return
+	}
+	var req CookRequest
+	json.NewDecoder(r.Body).Decode(&req)
+	if !strings.HasSuffix(r.URL.Path, "/cook/"+req.OrderID.String()) {
+		http.Error(w, "Order not found", http.StatusNotFound)
+		return
+	}
+	k.updates = append(k.updates, req)
+	w.WriteHeader(http.StatusOK)
+}
+
+// modifySetup creates a store backed by a fake kitchen and the fake payment provider, with a
+// router for placing, changing and tracking orders.
+func modifySetup(t *testing.T) (*Store, *chi.Mux, *fakeKitchen, *FakePaymentProvider) {
+	t.Helper()
+	kitchen := &fakeKitchen{}
+	kitchenServer := httptest.NewServer(kitchen)
+	t.Cleanup(kitchenServer.Close)
+	payments := NewFakePaymentProvider(DefaultFakePaymentConfig())
+
+	store := NewStore()
+	store.SetKitchenURL(kitchenServer.URL)
+	store.SetPaymentProvider(payments)
+	store.SetDeliveryURL("http://127.0.0.1:0")
+
+	router := chi.NewRouter()
+	router.Post("/order", store.HandleCreateOrder)
+	router.Patch("/order/{orderId}", store.HandleUpdateOrder)
+	router.Post("/events", store.HandleEvent)
+	return store, router, kitchen, payments
+}
+
+// placeMargherita places an order of one Margherita and returns it.
+func placeMargherita(t *testing.T, router http.Handler, promoCode string) Order {
+	t.Helper()
+	rec := sendJSON(router, http.MethodPost, "/order", CreateOrderRequest{
+		OrderItems: []OrderItem{{PizzaType: "Margherita", Quantity: 1}},
+		PromoCode:  promoCode,
+	})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status 201 Created, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var order Order
+	json.NewDecoder(rec.Body).Decode(&order)
+	return order
+}
+
+// TestUpdatePendingOrder verifies that the items of a pending order can be changed: the order is
+// priced again, the kitchen gets the new items, the new total is authorized in place of the old
+// payment and a MODIFIED event is recorded.
+func TestUpdatePendingOrder(t *testing.T) {
+	store, router, kitchen, payments := modifySetup(t)
+	order := placeMargherita(t, router, "")
+
+	items := []OrderItem{{PizzaType: "Margherita", Quantity: 2}, {PizzaType: "Pepperoni", Quantity: 1}}
+	rec := sendJSON(router, http.MethodPatch, "/order/"+order.OrderID.String(), UpdateOrderRequest{OrderItems: items})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200 OK, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var updated Order
+	json.NewDecoder(rec.Body).Decode(&updated)
+	if len(updated.OrderItems) != 2 || updated.Pricing.Subtotal != 3500 || updated.Pricing.Total != 3780 {
+		t.Errorf("expected 2 lines with a total of 3780, got %+v", updated.Pricing)
+	}
+	if updated.Payment == nil || updated.Payment.Amount != 3780 || updated.Payment.ID == order.Payment.ID {
+		t.Errorf("expected a new payment of 3780, got %+v", updated.Payment)
+	}
+
+	kitchen.mu.Lock()
+	if len(kitchen.updates) != 1 || len(kitchen.updates[0].OrderItems) != 2 {
+		t.Errorf("expected the kitchen to get the new items, got %+v", kitchen.updates)
+	}
+	kitchen.mu.Unlock()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		payments.mu.Lock()
+		status := payments.payments[order.Payment.ID]
+		payments.mu.Unlock()
+		if status == PaymentRefunded {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the old payment to be voided, got '%s'", status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	events := store.GetOrderEvents(order.OrderID)
+	if len(events) == 0 || events[len(events)-1].Status != StatusModified {
+		t.Errorf("expected a MODIFIED event, got %+v", events)
+	}
+}
+
+// TestUpdateOrderWhileKitchenAtCapacity verifies that a pending order still waiting for room in
+// a kitchen at capacity can be changed, and that the kitchen gets the new items once it has room.
+func TestUpdateOrderWhileKitchenAtCapacity(t *testing.T) {
+	store, router, kitchen, _ := modifySetup(t)
+	store.SetKitchenRetryPolicy(100, 10*time.Millisecond)
+	kitchen.mu.Lock()
+	kitchen.full = true
+	kitchen.mu.Unlock()
+	order := placeMargherita(t, router, "")
+
+	items := []OrderItem{{PizzaType: "Pepperoni", Quantity: 2}}
+	rec := sendJSON(router, http.MethodPatch, "/order/"+order.OrderID.String(), UpdateOrderRequest{OrderItems: items})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200 OK, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var updated Order
+	json.NewDecoder(rec.Body).Decode(&updated)
+	if updated.OrderStatus != "pending" || len(updated.OrderItems) != 1 || updated.OrderItems[0].PizzaType != "Pepperoni" {
+		t.Errorf("expected the pending order to have the new items, got %+v", updated)
+	}
+
+	kitchen.mu.Lock()
+	kitchen.full = false
+	kitchen.mu.Unlock()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		kitchen.mu.Lock()
+		accepted := append([]CookRequest(nil), kitchen.accepted...)
+		kitchen.mu.Unlock()
+		if len(accepted) > 0 {
+			if got := accepted[0].OrderItems; len(got) != 1 || got[0] != items[0] {
+				t.Errorf("expected the kitchen to cook the new items, got %+v", got)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the kitchen to accept the order once it had room")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestUpdateOrderRejectedOnceCookingStarted verifies that an order cannot be changed once the
+// kitchen reports cooking progress, or when the kitchen has already started on it, and that a
+// rejected change leaves the order as it was.
+func TestUpdateOrderRejectedOnceCookingStarted(t *testing.T) {
+	store, router, kitchen, _ := modifySetup(t)
+	items := UpdateOrderRequest{OrderItems: []OrderItem{{PizzaType: "Pepperoni", Quantity: 3}}}
+
+	queued := placeMargherita(t, router, "")
+	kitchen.mu.Lock()
+	kitchen.started = true
+	kitchen.mu.Unlock()
+	if rec := sendJSON(router, http.MethodPatch, "/order/"+queued.OrderID.String(), items); rec.Code != http.StatusConflict {
+		t.Errorf("expected status 409 Conflict when the kitchen has started, got %d", rec.Code)
+	}
+	got, _ := store.GetOrder(queued.OrderID)
+	store.mu.RLock()
+	if got.OrderItems[0].PizzaType != "Margherita" || got.Pricing.Total != 1080 || got.Payment.ID != queued.Payment.ID {
+		t.Errorf("expected the order to be unchanged, got %+v", got)
+	}
+	store.mu.RUnlock()
+
+	kitchen.mu.Lock()
+	kitchen.started = false
+	kitchen.mu.Unlock()
+	cooking := placeMargherita(t, router, "")
+	postEvent(router, OrderEvent{OrderID: cooking.OrderID, Status: "prep 10%", Source: "kitchen"})
+	if rec := sendJSON(router, http.MethodPatch, "/order/"+cooking.OrderID.String(), items); rec.Code != http.StatusConflict {
+		t.Errorf("expected status 409 Conflict for an order being cooked, got %d", rec.Code)
+	}
+	kitchen.mu.Lock()
+	if len(kitchen.updates) != 0 {
+		t.Errorf("expected no updates to reach the kitchen, got %+v", kitchen.updates)
+	}
+	kitchen.mu.Unlock()
+
+	if rec := sendJSON(router, http.MethodPatch, "/order/"+uuid.New().String(), items); rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 Not Found for an unknown order, got %d", rec.Code)
+	}
+}
+
+// TestUpdateOrderInvalidItems verifies that changes with no items, items off the menu or items
+// the order's promo code does not apply to are rejected.
+func TestUpdateOrderInvalidItems(t *testing.T) {
+	store, router, _, _ := modifySetup(t)
+	store.promotions["PEPPERONI2"] = &Promotion{Code: "PEPPERONI2", Kind: PromoBuyNGetOne, PizzaType: "Pepperoni", BuyQuantity: 1, uses: map[string]int{}}
+
+	rec := sendJSON(router, http.MethodPost, "/order", CreateOrderRequest{
+		OrderItems: []OrderItem{{PizzaType: "Pepperoni", Quantity: 2}},
+		PromoCode:  "PEPPERONI2",
+	})
+	var order Order
+	json.NewDecoder(rec.Body).Decode(&order)
+
+	tests := []struct {
+		name   string
+		items  []OrderItem
+		status int
+	}{
+		{"no items", nil, http.StatusBadRequest},
+		{"off the menu", []OrderItem{{PizzaType: "Calzone", Quantity: 1}}, http.StatusBadRequest},
+		{"promo no longer applies", []OrderItem{{PizzaType: "Margherita", Quantity: 2}}, http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := sendJSON(router, http.MethodPatch, "/order/"+order.OrderID.String(), UpdateOrderRequest{OrderItems: tt.items})
+			if rec.Code != tt.status {
+				t.Errorf("expected status %d, got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+// TestUpdateScheduledOrder verifies that a scheduled order is changed in the store only and sent
+// to the kitchen with its new items when it is dispatched.
+func TestUpdateScheduledOrder(t *testing.T) {
+	store, router, cookRequests := scheduleSetup(t)
+	router.Patch("/order/{orderId}", store.HandleUpdateOrder)
+	order := scheduleOrder(t, router, time.Now().Add(400*time.Millisecond))
+
+	items := []OrderItem{{PizzaType: "Veggie", Quantity: 2}}
+	rec := sendJSON(router, http.MethodPatch, "/order/"+order.OrderID.String(), UpdateOrderRequest{OrderItems: items})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200 OK, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	select {
+	case req := <-cookRequests:
+		if len(req.OrderItems) != 1 || req.OrderItems[0].PizzaType != "Veggie" || req.OrderItems[0].Quantity != 2 {
+			t.Errorf("expected the new items to be cooked, got %+v", req.OrderItems)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for the scheduled order to be dispatched")
+	}
+}
@@ -0,0 +1,252 @@
+package store
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// Sort orders accepted by GET /orders. A leading "-" sorts in descending order.
+const (
+	SortCreatedAt     = "createdAt"
+	SortCreatedAtDesc = "-createdAt"
+	SortTotal         = "total"
+	SortTotalDesc     = "-total"
+)
+
+// OrderDetails is an order together with the events received for it.
+type OrderDetails struct {
+	*Order
+	Events []OrderEvent `json:"events"`
+}
+
+// HandleGetOrder handles GET /order/{orderId} requests. Returns the order with
+// its event history, or 404 if the order is unknown.
+func (s *Store) HandleGetOrder(w http.ResponseWriter, r *http.Request) {
+	orderID, err := uuid.Parse(chi.URLParam(r, "orderId"))
+	if err != nil {
+		http.Error(w, "Invalid orderId format", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	order, exists := s.orders[orderID]
+	if !exists {
+		http.Error(w, "Order not found", http.StatusNotFound)
+		return
+	}
+	details := OrderDetails{Order: order, Events: s.events[orderID]}
+	if details.Events == nil {
+		details.Events = []OrderEvent{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(details); err != nil {
+		slog.Error("failed to encode order", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// orderQuery is a parsed GET /orders request.
+type orderQuery struct {
+	statuses      []string
+	pizzaType     string
+	customerID    *uuid.UUID
+	createdAfter  *time.Time
+	createdBefore *time.Time
+	sort          string
+	limit         int
+	cursor        *orderCursor
+}
+
+// orderCursor marks the last order of a page: the value of the sort key and
+// the order ID, which breaks ties between orders with the same key.
+type orderCursor struct {
+	Key     int64     `json:"k"`
+	OrderID uuid.UUID `json:"id"`
+}
+
+// encode returns the cursor as an opaque string.
+func (c orderCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeOrderCursor parses a cursor returned by encode.
+func decodeOrderCursor(s string) (*orderCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	var c orderCursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// parseOrderQuery reads the filters, sort order and page of a GET /orders
+// request.
+func parseOrderQuery(values url.Values) (orderQuery, error) {
+	q := orderQuery{
+		pizzaType: values.Get("pizzaType"),
+		sort:      SortCreatedAtDesc,
+	}
+	if v := values.Get("status"); v != "" {
+		q.statuses = strings.Split(v, ",")
+	}
+	if v := values.Get("customerId"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			return q, errors.New("invalid customerId")
+		}
+		q.customerID = &id
+	}
+	var err error
+	if q.createdAfter, err = parseTimeParam(values, "createdAfter"); err != nil {
+		return q, err
+	}
+	if q.createdBefore, err = parseTimeParam(values, "createdBefore"); err != nil {
+		return q, err
+	}
+	if v := values.Get("sort"); v != "" {
+		switch v {
+		case SortCreatedAt, SortCreatedAtDesc, SortTotal, SortTotalDesc:
+			q.sort = v
+		default:
+			return q, fmt.Errorf("invalid sort %q", v)
+		}
+	}
+	if v := values.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return q, errors.New("invalid limit")
+		}
+		q.limit = min(n, MaxOrderPageSize)
+	}
+	if v := values.Get("cursor"); v != "" {
+		c, err := decodeOrderCursor(v)
+		if err != nil {
+			return q, errors.New("invalid cursor")
+		}
+		q.cursor = c
+	}
+	return q, nil
+}
+
+// parseTimeParam reads an optional RFC 3339 timestamp from the query.
+func parseTimeParam(values url.Values, name string) (*time.Time, error) {
+	v := values.Get(name)
+	if v == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s, expected an RFC 3339 timestamp", name)
+	}
+	return &t, nil
+}
+
+// matches reports whether the order passes the query's filters.
+func (q orderQuery) matches(order *Order) bool {
+	if len(q.statuses) > 0 && !slices.Contains(q.statuses, order.OrderStatus) {
+		return false
+	}
+	if q.pizzaType != "" && !slices.ContainsFunc(order.OrderItems, func(item OrderItem) bool { return item.PizzaType == q.pizzaType }) {
+		return false
+	}
+	if q.customerID != nil && (order.CustomerID == nil || *order.CustomerID != *q.customerID) {
+		return false
+	}
+	if q.createdAfter != nil && order.CreatedAt.Before(*q.createdAfter) {
+		return false
+	}
+	if q.createdBefore != nil && !order.CreatedAt.Before(*q.createdBefore) {
+		return false
+	}
+	return true
+}
+
+// key returns the value the query sorts the order by.
+func (q orderQuery) key(order *Order) int64 {
+	if q.sort == SortTotal || q.sort == SortTotalDesc {
+		return int64(order.Pricing.Total)
+	}
+	return order.CreatedAt.UnixNano()
+}
+
+// before reports whether an order with key a and ID idA comes before one with
+// key b and ID idB in the query's sort order.
+func (q orderQuery) before(a int64, idA uuid.UUID, b int64, idB uuid.UUID) bool {
+	if a != b {
+		if strings.HasPrefix(q.sort, "-") {
+			return a > b
+		}
+		return a < b
+	}
+	return idA.String() < idB.String()
+}
+
+// HandleGetOrders handles GET /orders requests to list orders, newest first.
+// Orders can be filtered by status (comma separated), pizzaType, customerId
+// and a createdAfter/createdBefore range, and sorted by createdAt or total
+// (prefixed with "-" for descending order). When a limit is given the orders
+// are returned a page at a time: the X-Next-Cursor header holds the cursor
+// of the next page, passed back with the cursor query parameter.
+func (s *Store) HandleGetOrders(w http.ResponseWriter, r *http.Request) {
+	q, err := parseOrderQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid query: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	s.writeOrderPage(w, q)
+}
+
+// writeOrderPage writes the page of orders selected by q, with the cursor of
+// the next page in the X-Next-Cursor header. The caller must hold s.mu.
+func (s *Store) writeOrderPage(w http.ResponseWriter, q orderQuery) {
+	orders := make([]*Order, 0, len(s.orders))
+	for _, order := range s.orders {
+		if !q.matches(order) {
+			continue
+		}
+		if q.cursor != nil && !q.before(q.cursor.Key, q.cursor.OrderID, q.key(order), order.OrderID) {
+			continue
+		}
+		orders = append(orders, order)
+	}
+	sort.Slice(orders, func(i, j int) bool {
+		return q.before(q.key(orders[i]), orders[i].OrderID, q.key(orders[j]), orders[j].OrderID)
+	})
+
+	if q.limit > 0 && len(orders) > q.limit {
+		orders = orders[:q.limit]
+		last := orders[len(orders)-1]
+		w.Header().Set("X-Next-Cursor", orderCursor{Key: q.key(last), OrderID: last.OrderID}.encode())
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(orders); err != nil {
+		slog.Error("failed to encode orders", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,186 @@
+package store
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// seedOrders stores orders created a minute apart, starting an hour ago, with the given
+// statuses, pizza types and totals.
+func seedOrders(store *Store, customerID *uuid.UUID, orders ...Order) []*Order {
+	start := time.Now().Add(-time.Hour)
+	seeded := make([]*Order, 0, len(orders))
+	for i := range orders {
+		order := orders[i]
+		order.OrderID = uuid.New()
+		order.CustomerID = customerID
+		order.CreatedAt = start.Add(time.Duration(i) * time.Minute)
+		store.orders[order.OrderID] = &order
+		seeded = append(seeded, &order)
+	}
+	return seeded
+}
+
+// listOrders sends GET /orders with the given query and decodes the response.
+func listOrders(t *testing.T, router http.Handler, query url.Values) ([]Order, string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/orders?"+query.Encode(), nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200 OK for %s, got %d: %s", query.Encode(), rec.Code, rec.Body.String())
+	}
+	var orders []Order
+	json.NewDecoder(rec.Body).Decode(&orders)
+	return orders, rec.Header().Get("X-Next-Cursor")
+}
+
+// margherita returns an order of the given number of Margheritas with the given status and total.
+func margherita(quantity int, status string, total int) Order {
+	return Order{
+		OrderItems:  []OrderItem{{PizzaType: "Margherita", Quantity: quantity}},
+		OrderStatus: status,
+		Pricing:     Pricing{Total: total},
+	}
+}
+
+// TestGetOrdersFilters verifies that GET /orders filters orders by status, pizza type, customer
+// and creation time, newest first.
+func TestGetOrdersFilters(t *testing.T) {
+	store := NewStore()
+	router := chi.NewRouter()
+	router.Get("/orders", store.HandleGetOrders)
+
+	alice := uuid.New()
+	seeded := seedOrders(store, nil,
+		margherita(1, "pending", 1080),
+		margherita(2, "DELIVERED", 2160),
+		Order{OrderItems: []OrderItem{{PizzaType: "Pepperoni", Quantity: 1}}, OrderStatus: "COOKED", Pricing: Pricing{Total: 1620}},
+	)
+	mine := seedOrders(store, &alice, margherita(3, "DELIVERED", 3240))
+	mine[0].CreatedAt = seeded[2].CreatedAt.Add(time.Minute)
+
+	orders, _ := listOrders(t, router, url.Values{})
+	if len(orders) != 4 || orders[0].OrderID != mine[0].OrderID || orders[3].OrderID != seeded[0].OrderID {
+		t.Errorf("expected all 4 orders newest first, got %d", len(orders))
+	}
+
+	orders, _ = listOrders(t, router, url.Values{"status": {"DELIVERED,COOKED"}})
+	if len(orders) != 3 {
+		t.Errorf("expected 3 delivered or cooked orders, got %d", len(orders))
+	}
+
+	orders, _ = listOrders(t, router, url.Values{"pizzaType": {"Pepperoni"}})
+	if len(orders) != 1 || orders[0].OrderID != seeded[2].OrderID {
+		t.Errorf("expected the Pepperoni order, got %+v", orders)
+	}
+
+	orders, _ = listOrders(t, router, url.Values{"customerId": {alice.String()}})
+	if len(orders) != 1 || orders[0].OrderID != mine[0].OrderID {
+		t.Errorf("expected Alice's order, got %+v", orders)
+	}
+
+	orders, _ = listOrders(t, router, url.Values{
+		"createdAfter":  {seeded[1].CreatedAt.Format(time.RFC3339Nano)},
+		"createdBefore": {mine[0].CreatedAt.Format(time.RFC3339Nano)},
+	})
+	if len(orders) != 2 || orders[0].OrderID != seeded[2].OrderID || orders[1].OrderID != seeded[1].OrderID {
+		t.Errorf("expected the 2nd and 3rd orders, got %+v", orders)
+	}
+}
+
+// TestGetOrdersCursorPagination verifies that GET /orders pages through orders sorted by total
+// with X-Next-Cursor, without repeating or skipping orders.
+func TestGetOrdersCursorPagination(t *testing.T) {
+	store := NewStore()
+	router := chi.NewRouter()
+	router.Get("/orders", store.HandleGetOrders)
+
+	seedOrders(store, nil,
+		margherita(1, "pending", 500),
+		margherita(1, "pending", 300),
+		margherita(1, "pending", 300),
+		margherita(1, "pending", 900),
+		margherita(1, "pending", 100),
+	)
+
+	var totals []int
+	query := url.Values{"sort": {SortTotal}, "limit": {"2"}}
+	for pages := 0; pages < 5; pages++ {
+		orders, cursor := listOrders(t, router, query)
+		for _, order := range orders {
+			totals = append(totals, order.Pricing.Total)
+		}
+		if cursor == "" {
+			break
+		}
+		query.Set("cursor", cursor)
+	}
+
+	expected := []int{100, 300, 300, 500, 900}
+	if len(totals) != len(expected) {
+		t.Fatalf("expected totals %v, got %v", expected, totals)
+	}
+	for i := range expected {
+		if totals[i] != expected[i] {
+			t.Errorf("expected totals %v, got %v", expected, totals)
+			break
+		}
+	}
+}
+
+// TestGetOrdersInvalidQuery verifies that GET /orders rejects invalid query parameters.
+func TestGetOrdersInvalidQuery(t *testing.T) {
+	store := NewStore()
+	router := chi.NewRouter()
+	router.Get("/orders", store.HandleGetOrders)
+
+	for _, query := range []string{"sort=price", "limit=0", "cursor=%25%25", "createdAfter=yesterday", "customerId=alice"} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/orders?"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400 Bad Request for %s, got %d", query, rec.Code)
+		}
+	}
+}
+
+// TestGetOrderIncludesEvents verifies that GET /order/{orderId} returns the order with its events.
+func TestGetOrderIncludesEvents(t *testing.T) {
+	store := NewStore()
+	router := chi.NewRouter()
+	router.Get("/order/{orderId}", store.HandleGetOrder)
+	router.Post("/events", store.HandleEvent)
+
+	order := seedOrders(store, nil, margherita(1, "pending", 1080))[0]
+	order.CreatedAt = time.Now()
+	postEvent(router, OrderEvent{OrderID: order.OrderID, Status: "cooking 50%", Source: "kitchen"})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/order/"+order.OrderID.String(), nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200 OK, got %d", rec.Code)
+	}
+	var details struct {
+		Order
+		Events []OrderEvent `json:"events"`
+	}
+	json.NewDecoder(rec.Body).Decode(&details)
+	if details.OrderID != order.OrderID || details.OrderStatus != "cooking 50%" {
+		t.Errorf("expected the order with its latest status, got %+v", details.Order)
+	}
+	if len(details.Events) != 1 || details.Events[0].Source != "kitchen" {
+		t.Errorf("expected the kitchen event, got %+v", details.Events)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/order/"+uuid.New().String(), nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 Not Found, got %d", rec.Code)
+	}
+}
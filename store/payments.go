@@ -0,0 +1,241 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Errors returned by payment providers.
+var (
+	ErrPaymentDeclined     = errors.New("payment declined")
+	ErrPaymentTimeout      = errors.New("payment provider timed out")
+	ErrPaymentNotFound     = errors.New("payment not found")
+	ErrInvalidPaymentState = errors.New("invalid payment state")
+)
+
+// Payment statuses.
+const (
+	PaymentAuthorized = "authorized"
+	PaymentCaptured   = "captured"
+	PaymentRefunded   = "refunded"
+)
+
+// PaymentProvider takes payments for orders. Orders are authorized for their
+// total when they are placed, captured once delivered and refunded when they
+// cannot be delivered. Amounts are in minor currency units.
+type PaymentProvider interface {
+	// Authorize reserves the amount for the order and returns the payment ID.
+	Authorize(ctx context.Context, orderID uuid.UUID, amount int, currency string) (string, error)
+	// Capture collects an authorized payment.
+	Capture(ctx context.Context, paymentID string) error
+	// Refund returns an authorized or captured payment to the customer.
+	Refund(ctx context.Context, paymentID string) error
+}
+
+// Payment is the payment of an order as recorded by the store.
+type Payment struct {
+	ID           string     `json:"id"`
+	Status       string     `json:"status"`
+	Amount       int        `json:"amount"`
+	Currency     string     `json:"currency"`
+	AuthorizedAt time.Time  `json:"authorizedAt"`
+	CapturedAt   *time.Time `json:"capturedAt,omitempty"`
+	RefundedAt   *time.Time `json:"refundedAt,omitempty"`
+}
+
+// Fake payment provider modes.
+const (
+	PaymentModeSucceed = "succeed"
+	PaymentModeDecline = "decline"
+	PaymentModeTimeout = "timeout"
+)
+
+// DefaultPaymentTimeout is how long the fake payment provider hangs before
+// timing out.
+const DefaultPaymentTimeout = 5 * time.Second
+
+// FakePaymentConfig configures the fake payment provider. Mode decides the
+// outcome of authorizations: they succeed, are declined, or hang for Timeout
+// (or until the context is done) and then time out.
+type FakePaymentConfig struct {
+	Mode    string
+	Timeout time.Duration
+}
+
+// DefaultFakePaymentConfig returns a fake payment provider configuration that
+// authorizes every payment.
+func DefaultFakePaymentConfig() FakePaymentConfig {
+	return FakePaymentConfig{
+		Mode:    PaymentModeSucceed,
+		Timeout: DefaultPaymentTimeout,
+	}
+}
+
+// FakePaymentProvider is a local, in-memory PaymentProvider used to simulate
+// payments without a real payment gateway.
+type FakePaymentProvider struct {
+	mu       sync.Mutex
+	mode     string
+	timeout  time.Duration
+	payments map[string]string
+}
+
+// NewFakePaymentProvider creates a fake payment provider with the given
+// configuration. Unknown modes behave like PaymentModeSucceed.
+func NewFakePaymentProvider(cfg FakePaymentConfig) *FakePaymentProvider {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultPaymentTimeout
+	}
+	return &FakePaymentProvider{
+		mode:     cfg.Mode,
+		timeout:  cfg.Timeout,
+		payments: make(map[string]string),
+	}
+}
+
+// Authorize reserves the amount for the order according to the provider's mode.
+func (p *FakePaymentProvider) Authorize(ctx context.Context, orderID uuid.UUID, amount int, currency string) (string, error) {
+	switch p.mode {
+	case PaymentModeDecline:
+		return "", ErrPaymentDeclined
+	case PaymentModeTimeout:
+		select {
+		case <-ctx.Done():
+		case <-time.After(p.timeout):
+		}
+		return "", ErrPaymentTimeout
+	}
+
+	id := "pay_" + uuid.NewString()
+	p.mu.Lock()
+	p.payments[id] = PaymentAuthorized
+	p.mu.Unlock()
+	return id, nil
+}
+
+// Capture collects an authorized payment.
+func (p *FakePaymentProvider) Capture(ctx context.Context, paymentID string) error {
+	return p.transition(paymentID, PaymentCaptured, PaymentAuthorized)
+}
+
+// Refund returns an authorized or captured payment.
+func (p *FakePaymentProvider) Refund(ctx context.Context, paymentID string) error {
+	return p.transition(paymentID, PaymentRefunded, PaymentAuthorized, PaymentCaptured)
+}
+
+// transition moves a payment to the given status if it is in one of the
+// allowed statuses.
+func (p *FakePaymentProvider) transition(paymentID, status string, from ...string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	current, exists := p.payments[paymentID]
+	if !exists {
+		return ErrPaymentNotFound
+	}
+	for _, allowed := range from {
+		if current == allowed {
+			p.payments[paymentID] = status
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: cannot move payment from %s to %s", ErrInvalidPaymentState, current, status)
+}
+
+// SetPaymentProvider sets the provider that takes payments for orders.
+func (s *Store) SetPaymentProvider(provider PaymentProvider) {
+	s.payments = provider
+}
+
+// authorizePayment authorizes the total of an order and records the payment
+// on it.
+func (s *Store) authorizePayment(ctx context.Context, order *Order) error {
+	paymentID, err := s.payments.Authorize(ctx, order.OrderID, order.Pricing.Total, order.Pricing.Currency)
+	if err != nil {
+		return err
+	}
+	order.Payment = &Payment{
+		ID:           paymentID,
+		Status:       PaymentAuthorized,
+		Amount:       order.Pricing.Total,
+		Currency:     order.Pricing.Currency,
+		AuthorizedAt: time.Now(),
+	}
+	return nil
+}
+
+// capturePayment collects the payment of a delivered order.
+func (s *Store) capturePayment(ctx context.Context, orderID uuid.UUID) {
+	payment, exists := s.orderPayment(orderID)
+	if !exists || payment.Status != PaymentAuthorized {
+		return
+	}
+	if err := s.payments.Capture(ctx, payment.ID); err != nil {
+		slog.Error("failed to capture payment", "orderId", orderID, "paymentId", payment.ID, "error", err)
+		return
+	}
+
+	s.setPaymentStatus(orderID, PaymentCaptured)
+	slog.Info("payment captured", "orderId", orderID, "paymentId", payment.ID, "amount", payment.Amount)
+}
+
+// refundPayment returns the payment of an order that will not be delivered.
+func (s *Store) refundPayment(ctx context.Context, orderID uuid.UUID) {
+	payment, exists := s.orderPayment(orderID)
+	if !exists || payment.Status == PaymentRefunded {
+		return
+	}
+	if err := s.payments.Refund(ctx, payment.ID); err != nil {
+		slog.Error("failed to refund payment", "orderId", orderID, "paymentId", payment.ID, "error", err)
+		return
+	}
+
+	s.setPaymentStatus(orderID, PaymentRefunded)
+	slog.Info("payment refunded", "orderId", orderID, "paymentId", payment.ID, "amount", payment.Amount)
+}
+
+// voidPayment refunds an authorization that is no longer the payment of its
+// order, because the order was priced again or the change was rejected.
+func (s *Store) voidPayment(ctx context.Context, orderID uuid.UUID, paymentID string) {
+	if err := s.payments.Refund(ctx, paymentID); err != nil {
+		slog.Error("failed to void payment", "orderId", orderID, "paymentId", paymentID, "error", err)
+		return
+	}
+	slog.Info("payment voided", "orderId", orderID, "paymentId", paymentID)
+}
+
+// orderPayment returns a copy of the payment recorded on an order, and
+// whether the order exists and has a payment.
+func (s *Store) orderPayment(orderID uuid.UUID) (Payment, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	order, exists := s.orders[orderID]
+	if !exists || order.Payment == nil {
+		return Payment{}, false
+	}
+	return *order.Payment, true
+}
+
+// setPaymentStatus records a captured or refunded payment on its order.
+func (s *Store) setPaymentStatus(orderID uuid.UUID, status string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	order, exists := s.orders[orderID]
+	if !exists || order.Payment == nil {
+		return
+	}
+	now := time.Now()
+	order.Payment.Status = status
+	switch status {
+	case PaymentCaptured:
+		order.Payment.CapturedAt = &now
+	case PaymentRefunded:
+		order.Payment.RefundedAt = &now
+	}
+}
@@ -0,0 +1,137 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// waitForPaymentStatus waits until the payment of the order has the given status.
+func waitForPaymentStatus(t *testing.T, store *Store, orderID uuid.UUID, status string) Payment {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		payment, exists := store.orderPayment(orderID)
+		if exists && payment.Status == status {
+			return payment
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected payment status '%s', got %+v", status, payment)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestPostOrderAuthorizesPayment verifies that an order's total is authorized when it is placed
+// and captured once it is delivered.
+func TestPostOrderAuthorizesPayment(t *testing.T) {
+	store := NewStore()
+	router := chi.NewRouter()
+	router.Post("/order", store.HandleCreateOrder)
+	router.Post("/events", store.HandleEvent)
+
+	rec := sendJSON(router, http.MethodPost, "/order", CreateOrderRequest{OrderItems: []OrderItem{{PizzaType: "Margherita", Quantity: 2}}})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status 201 Created, got %d", rec.Code)
+	}
+	var order Order
+	json.NewDecoder(rec.Body).Decode(&order)
+	if order.Payment == nil || order.Payment.Status != PaymentAuthorized || order.Payment.Amount != order.Pricing.Total {
+		t.Fatalf("expected the order total to be authorized, got %+v", order.Payment)
+	}
+
+	postEvent(router, OrderEvent{OrderID: order.OrderID, Status: "DELIVERED", Source: "delivery"})
+
+	payment := waitForPaymentStatus(t, store, order.OrderID, PaymentCaptured)
+	if payment.CapturedAt == nil {
+		t.Error("expected the capture time to be recorded")
+	}
+}
+
+// TestPostOrderPaymentDeclined verifies that a declined payment is rejected with 402 Payment
+// Required, the order is not placed and its promo code redemption is given back.
+func TestPostOrderPaymentDeclined(t *testing.T) {
+	store := NewStore()
+	store.SetPaymentProvider(NewFakePaymentProvider(FakePaymentConfig{Mode: PaymentModeDecline}))
+	router := promotionsRouter(store)
+	sendJSON(router, http.MethodPost, "/promotions", Promotion{Code: "ONCE", Kind: PromoFixedAmount, Amount: 100, MaxUsesPerCustomer: 1})
+	alice := addCustomer(store, "Alice")
+
+	rec := sendJSON(router, http.MethodPost, "/order", CreateOrderRequest{
+		OrderItems: []OrderItem{{PizzaType: "Margherita", Quantity: 1}},
+		PromoCode:  "ONCE",
+		CustomerID: &alice,
+	})
+	if rec.Code != http.StatusPaymentRequired {
+		t.Errorf("expected status 402 Payment Required, got %d", rec.Code)
+	}
+	if len(store.orders) != 0 {
+		t.Errorf("expected no orders to be stored, got %d", len(store.orders))
+	}
+	if promo := store.promotions["ONCE"]; promo.Redemptions != 0 || promo.uses[alice.String()] != 0 {
+		t.Errorf("expected the promo code redemption to be given back, got %d redemptions", promo.Redemptions)
+	}
+}
+
+// TestPostOrderPaymentTimeout verifies that a payment provider that does not answer results in
+// 504 Gateway Timeout.
+func TestPostOrderPaymentTimeout(t *testing.T) {
+	store := NewStore()
+	store.SetPaymentProvider(NewFakePaymentProvider(FakePaymentConfig{Mode: PaymentModeTimeout, Timeout: 50 * time.Millisecond}))
+	router := chi.NewRouter()
+	router.Post("/order", store.HandleCreateOrder)
+
+	rec := sendJSON(router, http.MethodPost, "/order", CreateOrderRequest{OrderItems: []OrderItem{{PizzaType: "Margherita", Quantity: 1}}})
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Errorf("expected status 504 Gateway Timeout, got %d", rec.Code)
+	}
+	if len(store.orders) != 0 {
+		t.Errorf("expected no orders to be stored, got %d", len(store.orders))
+	}
+}
+
+// TestPaymentRefundedWhenDeliveryAttemptsExhausted verifies that the payment of an order that
+// could not be delivered is refunded.
+func TestPaymentRefundedWhenDeliveryAttemptsExhausted(t *testing.T) {
+	store, router, order, _ := deliveryFailureSetup(t, 1)
+	if err := store.authorizePayment(context.Background(), order); err != nil {
+		t.Fatalf("failed to authorize payment: %v", err)
+	}
+
+	postEvent(router, OrderEvent{OrderID: order.OrderID, Status: StatusDeliveryFailed, Source: "delivery", Reason: FailureCustomerNotHome})
+
+	payment := waitForPaymentStatus(t, store, order.OrderID, PaymentRefunded)
+	if payment.RefundedAt == nil {
+		t.Error("expected the refund time to be recorded")
+	}
+}
+
+// TestFakePaymentProviderTransitions verifies that the fake provider only captures authorized
+// payments and refunds each payment once.
+func TestFakePaymentProviderTransitions(t *testing.T) {
+	provider := NewFakePaymentProvider(DefaultFakePaymentConfig())
+	ctx := context.Background()
+
+	paymentID, err := provider.Authorize(ctx, uuid.New(), 1000, DefaultCurrency)
+	if err != nil {
+		t.Fatalf("expected authorization to succeed, got %v", err)
+	}
+	if err := provider.Capture(ctx, paymentID); err != nil {
+		t.Errorf("expected capture to succeed, got %v", err)
+	}
+	if err := provider.Refund(ctx, paymentID); err != nil {
+		t.Errorf("expected refund to succeed, got %v", err)
+	}
+	if err := provider.Refund(ctx, paymentID); !errors.Is(err, ErrInvalidPaymentState) {
+		t.Errorf("expected a second refund to fail with ErrInvalidPaymentState, got %v", err)
+	}
+	if err := provider.Capture(ctx, "pay_unknown"); !errors.Is(err, ErrPaymentNotFound) {
+		t.Errorf("expected ErrPaymentNotFound for an unknown payment, got %v", err)
+	}
+}
@@ -0,0 +1,140 @@
+package store
+
+import "fmt"
+
+// DefaultCurrency is the ISO 4217 code of the currency orders are priced in.
+const DefaultCurrency = "USD"
+
+// TaxRule is a tax applied to an order. Rate is in basis points (800 is 8%),
+// and OnItems and OnDelivery select whether it is charged on the pizzas, on
+// the delivery fee, or on both. Tips are never taxed.
+type TaxRule struct {
+	Name       string `json:"name"`
+	Rate       int    `json:"rate"`
+	OnItems    bool   `json:"onItems"`
+	OnDelivery bool   `json:"onDelivery"`
+}
+
+// PriceLine is the price of one line of an order.
+type PriceLine struct {
+	PizzaType string `json:"pizzaType"`
+	Quantity  int    `json:"quantity"`
+	UnitPrice int    `json:"unitPrice"`
+	Amount    int    `json:"amount"`
+}
+
+// TaxLine is the amount charged for one tax rule on its taxable base.
+type TaxLine struct {
+	Name   string `json:"name"`
+	Rate   int    `json:"rate"`
+	Base   int    `json:"base"`
+	Amount int    `json:"amount"`
+}
+
+// Pricing is the itemized price breakdown of an order. All amounts are in
+// minor currency units (cents) to avoid floating point rounding errors.
+type Pricing struct {
+	Currency    string      `json:"currency"`
+	Lines       []PriceLine `json:"lines"`
+	Subtotal    int         `json:"subtotal"`
+	PromoCode   string      `json:"promoCode,omitempty"`
+	Discount    int         `json:"discount"`
+	DeliveryFee int         `json:"deliveryFee"`
+	Taxes       []TaxLine   `json:"taxes"`
+	TaxTotal    int         `json:"taxTotal"`
+	Tip         int         `json:"tip"`
+	Total       int         `json:"total"`
+}
+
+// DefaultMenu returns the price in cents of each pizza on the menu.
+func DefaultMenu() map[string]int {
+	return map[string]int{
+		"Margherita": 1000,
+		"Pepperoni":  1500,
+		"Hawaiian":   1500,
+		"Vegan":      1200,
+		"Veggie":     1200,
+	}
+}
+
+// DefaultTaxRules returns the sales tax charged on pizzas and delivery.
+func DefaultTaxRules() []TaxRule {
+	return []TaxRule{
+		{Name: "Sales tax", Rate: 800, OnItems: true, OnDelivery: true},
+	}
+}
+
+// SetMenu sets the price in cents of each pizza the store sells.
+func (s *Store) SetMenu(menu map[string]int) {
+	s.menu = menu
+}
+
+// SetTaxRules sets the taxes applied to orders.
+func (s *Store) SetTaxRules(rules []TaxRule) {
+	s.taxRules = rules
+}
+
+// validateItems checks that every item is on the menu with a positive quantity.
+func (s *Store) validateItems(items []OrderItem) error {
+	for _, item := range items {
+		if _, ok := s.menu[item.PizzaType]; !ok {
+			return fmt.Errorf("unknown pizza type %q", item.PizzaType)
+		}
+		if item.Quantity <= 0 {
+			return fmt.Errorf("invalid quantity %d for %s", item.Quantity, item.PizzaType)
+		}
+	}
+	return nil
+}
+
+// priceOrder computes the price breakdown of an order from the menu, the
+// promotion (if any), the delivery fee, the tax rules and the tip. Discounts
+// come off the pizzas before they are taxed. The items must have been checked
+// with validateItems.
+func (s *Store) priceOrder(items []OrderItem, deliveryFee, tip int, promo *Promotion) Pricing {
+	pricing := Pricing{
+		Currency:    DefaultCurrency,
+		Lines:       make([]PriceLine, 0, len(items)),
+		DeliveryFee: deliveryFee,
+		Taxes:       make([]TaxLine, 0, len(s.taxRules)),
+		Tip:         tip,
+	}
+
+	for _, item := range items {
+		price := s.menu[item.PizzaType]
+		line := PriceLine{
+			PizzaType: item.PizzaType,
+			Quantity:  item.Quantity,
+			UnitPrice: price,
+			Amount:    price * item.Quantity,
+		}
+		pricing.Lines = append(pricing.Lines, line)
+		pricing.Subtotal += line.Amount
+	}
+	if promo != nil {
+		pricing.PromoCode = promo.Code
+		pricing.Discount = promo.discount(pricing.Lines, pricing.Subtotal)
+	}
+
+	for _, rule := range s.taxRules {
+		base := 0
+		if rule.OnItems {
+			base += pricing.Subtotal - pricing.Discount
+		}
+		if rule.OnDelivery {
+			base += pricing.DeliveryFee
+		}
+		tax := TaxLine{Name: rule.Name, Rate: rule.Rate, Base: base, Amount: applyRate(base, rule.Rate)}
+		pricing.Taxes = append(pricing.Taxes, tax)
+		pricing.TaxTotal += tax.Amount
+	}
+
+	pricing.Total = pricing.Subtotal - pricing.Discount + pricing.DeliveryFee + pricing.TaxTotal + pricing.Tip
+	return pricing
+}
+
+// applyRate returns amount times rate basis points, rounded half up to the
+// nearest cent.
+func applyRate(amount, rate int) int {
+	return (amount*rate + 5000) / 10000
+}
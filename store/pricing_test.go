@@ -0,0 +1,119 @@
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// TestPriceOrderItemizesLinesTaxesAndTip verifies the price breakdown of an order with several lines.
+func TestPriceOrderItemizesLinesTaxesAndTip(t *testing.T) {
+	store := NewStore()
+	items := []OrderItem{
+		{PizzaType: "Margherita", Quantity: 2},
+		{PizzaType: "Pepperoni", Quantity: 1},
+	}
+
+	pricing := store.priceOrder(items, 299, 300, nil)
+
+	if len(pricing.Lines) != 2 {
+		t.Fatalf("expected 2 price lines, got %d", len(pricing.Lines))
+	}
+	if line := pricing.Lines[0]; line.UnitPrice != 1000 || line.Amount != 2000 {
+		t.Errorf("expected 2 Margherita at 1000 for 2000, got %+v", line)
+	}
+	if pricing.Subtotal != 3500 {
+		t.Errorf("expected subtotal 3500, got %d", pricing.Subtotal)
+	}
+	// 8% of 3500 + 299 = 303.92, rounded to 304
+	if len(pricing.Taxes) != 1 || pricing.Taxes[0].Base != 3799 || pricing.TaxTotal != 304 {
+		t.Errorf("expected 304 sales tax on 3799, got %+v", pricing.Taxes)
+	}
+	if pricing.Total != 3500+299+304+300 {
+		t.Errorf("expected total %d, got %d", 3500+299+304+300, pricing.Total)
+	}
+	if pricing.Currency != DefaultCurrency {
+		t.Errorf("expected currency '%s', got '%s'", DefaultCurrency, pricing.Currency)
+	}
+}
+
+// TestPriceOrderAppliesTaxRulesToTheirBase verifies that each tax rule is charged on items, delivery or both.
+func TestPriceOrderAppliesTaxRulesToTheirBase(t *testing.T) {
+	store := NewStore()
+	store.SetTaxRules([]TaxRule{
+		{Name: "Food tax", Rate: 1000, OnItems: true},
+		{Name: "Delivery tax", Rate: 2100, OnDelivery: true},
+	})
+
+	pricing := store.priceOrder([]OrderItem{{PizzaType: "Vegan", Quantity: 1}}, 199, 0, nil)
+
+	if pricing.Taxes[0].Base != 1200 || pricing.Taxes[0].Amount != 120 {
+		t.Errorf("expected food tax of 120 on 1200, got %+v", pricing.Taxes[0])
+	}
+	// 21% of 199 = 41.79, rounded to 42
+	if pricing.Taxes[1].Base != 199 || pricing.Taxes[1].Amount != 42 {
+		t.Errorf("expected delivery tax of 42 on 199, got %+v", pricing.Taxes[1])
+	}
+	if pricing.Total != 1200+199+120+42 {
+		t.Errorf("expected total %d, got %d", 1200+199+120+42, pricing.Total)
+	}
+}
+
+// TestPostOrderReturnsPricing verifies that POST /order and GET /orders return the order's pricing.
+func TestPostOrderReturnsPricing(t *testing.T) {
+	store := NewStore()
+	router := chi.NewRouter()
+	router.Post("/order", store.HandleCreateOrder)
+	router.Get("/orders", store.HandleGetOrders)
+
+	reqBody := CreateOrderRequest{
+		OrderItems: []OrderItem{{PizzaType: "Hawaiian", Quantity: 2}},
+		Tip:        250,
+	}
+	body, _ := json.Marshal(reqBody)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/order", bytes.NewReader(body)))
+
+	var created Order
+	json.NewDecoder(rec.Body).Decode(&created)
+	// 3000 + 8% tax (240) + tip
+	if created.Pricing.Subtotal != 3000 || created.Pricing.TaxTotal != 240 || created.Pricing.Tip != 250 || created.Pricing.Total != 3490 {
+		t.Errorf("unexpected pricing: %+v", created.Pricing)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/orders", nil))
+	var orders []Order
+	json.NewDecoder(rec.Body).Decode(&orders)
+	if len(orders) != 1 || orders[0].Pricing.Total != 3490 {
+		t.Errorf("expected GET /orders to return the pricing, got %+v", orders)
+	}
+}
+
+// TestPostOrderRejectsInvalidItemsAndTip verifies that POST /order returns 400 for pizzas not on the menu,
+// non-positive quantities and negative tips.
+func TestPostOrderRejectsInvalidItemsAndTip(t *testing.T) {
+	store := NewStore()
+	router := chi.NewRouter()
+	router.Post("/order", store.HandleCreateOrder)
+
+	for _, reqBody := range []CreateOrderRequest{
+		{OrderItems: []OrderItem{{PizzaType: "Calzone", Quantity: 1}}},
+		{OrderItems: []OrderItem{{PizzaType: "Margherita", Quantity: 0}}},
+		{OrderItems: []OrderItem{{PizzaType: "Margherita", Quantity: 1}}, Tip: -100},
+	} {
+		body, _ := json.Marshal(reqBody)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/order", bytes.NewReader(body)))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400 Bad Request for %+v, got %d", reqBody, rec.Code)
+		}
+	}
+	if len(store.orders) != 0 {
+		t.Errorf("expected no orders to be stored, got %d", len(store.orders))
+	}
+}
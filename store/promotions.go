@@ -0,0 +1,328 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Promotion kinds.
+const (
+	PromoPercentage  = "percentage"
+	PromoFixedAmount = "fixed"
+	PromoBuyNGetOne  = "buy_n_get_one"
+)
+
+// Errors returned when a promo code cannot be applied to an order.
+var (
+	ErrPromoNotFound         = errors.New("unknown promo code")
+	ErrPromoNotActive        = errors.New("promo code is not valid at this time")
+	ErrPromoMinimumNotMet    = errors.New("order does not reach the minimum amount")
+	ErrPromoNotApplicable    = errors.New("order has no items the promo code applies to")
+	ErrPromoCustomerRequired = errors.New("promo code requires a customer ID")
+	ErrPromoUsageExceeded    = errors.New("promo code usage limit reached")
+)
+
+// Promotion is a promo code customers can apply to their orders.
+//
+// Percentage codes take Rate basis points off the pizzas, fixed codes take
+// Amount cents off, and buy-N-get-one codes make one pizza of PizzaType free
+// for every BuyQuantity pizzas of that type bought. MinOrderAmount is the
+// pizza subtotal in cents an order must reach, ValidFrom and ValidUntil bound
+// when the code can be used, and MaxUsesPerCustomer limits how often each
+// customer can redeem it (0 means no limit).
+type Promotion struct {
+	Code               string     `json:"code"`
+	Kind               string     `json:"kind"`
+	Rate               int        `json:"rate,omitempty"`
+	Amount             int        `json:"amount,omitempty"`
+	PizzaType          string     `json:"pizzaType,omitempty"`
+	BuyQuantity        int        `json:"buyQuantity,omitempty"`
+	MinOrderAmount     int        `json:"minOrderAmount,omitempty"`
+	ValidFrom          *time.Time `json:"validFrom,omitempty"`
+	ValidUntil         *time.Time `json:"validUntil,omitempty"`
+	MaxUsesPerCustomer int        `json:"maxUsesPerCustomer,omitempty"`
+	Redemptions        int        `json:"redemptions"`
+
+	uses map[string]int
+}
+
+// Validate checks that the promotion is well formed.
+func (p *Promotion) Validate() error {
+	if p.Code == "" {
+		return errors.New("code is required")
+	}
+	switch p.Kind {
+	case PromoPercentage:
+		if p.Rate <= 0 || p.Rate > 10000 {
+			return errors.New("rate must be between 1 and 10000 basis points")
+		}
+	case PromoFixedAmount:
+		if p.Amount <= 0 {
+			return errors.New("amount must be positive")
+		}
+	case PromoBuyNGetOne:
+		if p.PizzaType == "" || p.BuyQuantity <= 0 {
+			return errors.New("pizzaType and a positive buyQuantity are required")
+		}
+	default:
+		return fmt.Errorf("unknown kind %q", p.Kind)
+	}
+	if p.MinOrderAmount < 0 || p.MaxUsesPerCustomer < 0 {
+		return errors.New("minOrderAmount and maxUsesPerCustomer must not be negative")
+	}
+	if p.ValidFrom != nil && p.ValidUntil != nil && !p.ValidUntil.After(*p.ValidFrom) {
+		return errors.New("validUntil must be after validFrom")
+	}
+	return nil
+}
+
+// active reports whether the promotion can be used at the given time.
+func (p *Promotion) active(now time.Time) bool {
+	if p.ValidFrom != nil && now.Before(*p.ValidFrom) {
+		return false
+	}
+	return p.ValidUntil == nil || now.Before(*p.ValidUntil)
+}
+
+// discount returns the amount in cents the promotion takes off the priced
+// pizzas, never more than their subtotal.
+func (p *Promotion) discount(lines []PriceLine, subtotal int) int {
+	var discount int
+	switch p.Kind {
+	case PromoPercentage:
+		discount = applyRate(subtotal, p.Rate)
+	case PromoFixedAmount:
+		discount = p.Amount
+	case PromoBuyNGetOne:
+		for _, line := range lines {
+			if line.PizzaType == p.PizzaType {
+				discount += line.Quantity / (p.BuyQuantity + 1) * line.UnitPrice
+			}
+		}
+	}
+	return min(discount, subtotal)
+}
+
+// normalizePromoCode makes promo codes case insensitive.
+func normalizePromoCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// redeemPromotion checks that the promo code applies to an order with the
+// given items placed by the customer, and records its use. It returns a copy
+// of the promotion to price the order with.
+func (s *Store) redeemPromotion(code, customerID string, items []OrderItem) (*Promotion, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	promo, exists := s.promotions[normalizePromoCode(code)]
+	if !exists {
+		return nil, ErrPromoNotFound
+	}
+	if !promo.active(time.Now()) {
+		return nil, ErrPromoNotActive
+	}
+
+	pricing := s.priceOrder(items, 0, 0, nil)
+	if pricing.Subtotal < promo.MinOrderAmount {
+		return nil, ErrPromoMinimumNotMet
+	}
+	if promo.discount(pricing.Lines, pricing.Subtotal) == 0 {
+		return nil, ErrPromoNotApplicable
+	}
+
+	if promo.MaxUsesPerCustomer > 0 {
+		if customerID == "" {
+			return nil, ErrPromoCustomerRequired
+		}
+		if promo.uses[customerID] >= promo.MaxUsesPerCustomer {
+			return nil, ErrPromoUsageExceeded
+		}
+	}
+	promo.uses[customerID]++
+	promo.Redemptions++
+
+	redeemed := *promo
+	return &redeemed, nil
+}
+
+// releasePromotion gives back a redemption of the promo code by the customer
+// when the order it was redeemed for is not placed.
+func (s *Store) releasePromotion(code, customerID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	promo, exists := s.promotions[normalizePromoCode(code)]
+	if !exists || promo.uses[customerID] == 0 {
+		return
+	}
+	promo.uses[customerID]--
+	promo.Redemptions--
+}
+
+// HandleCreatePromotion handles POST /promotions requests to add a promo code.
+// Returns 409 Conflict if the code already exists.
+func (s *Store) HandleCreatePromotion(w http.ResponseWriter, r *http.Request) {
+	var promo Promotion
+	if err := json.NewDecoder(r.Body).Decode(&promo); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	promo.Code = normalizePromoCode(promo.Code)
+	if err := promo.Validate(); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid promotion: %v", err), http.StatusBadRequest)
+		return
+	}
+	promo.Redemptions = 0
+	promo.uses = make(map[string]int)
+
+	s.mu.Lock()
+	if _, exists := s.promotions[promo.Code]; exists {
+		s.mu.Unlock()
+		http.Error(w, "Promotion already exists", http.StatusConflict)
+		return
+	}
+	s.promotions[promo.Code] = &promo
+	created := promo
+	s.mu.Unlock()
+
+	slog.Info("promotion created", "code", created.Code, "kind", created.Kind)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(created); err != nil {
+		slog.Error("failed to encode promotion", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleGetPromotions handles GET /promotions requests to list the promo
+// codes ordered by code.
+func (s *Store) HandleGetPromotions(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	promos := make([]Promotion, 0, len(s.promotions))
+	for _, promo := range s.promotions {
+		promos = append(promos, *promo)
+	}
+	s.mu.RUnlock()
+	sort.Slice(promos, func(i, j int) bool { return promos[i].Code < promos[j].Code })
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(promos); err != nil {
+		slog.Error("failed to encode promotions", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleGetPromotion handles GET /promotions/{code} requests.
+func (s *Store) HandleGetPromotion(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	promo, exists := s.promotions[normalizePromoCode(chi.URLParam(r, "code"))]
+	var found Promotion
+	if exists {
+		found = *promo
+	}
+	s.mu.RUnlock()
+
+	if !exists {
+		http.Error(w, "Promotion not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(found); err != nil {
+		slog.Error("failed to encode promotion", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleUpdatePromotion handles PUT /promotions/{code} requests to replace
+// the terms of a promo code. Redemptions made so far are kept.
+func (s *Store) HandleUpdatePromotion(w http.ResponseWriter, r *http.Request) {
+	var promo Promotion
+	if err := json.NewDecoder(r.Body).Decode(&promo); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	promo.Code = normalizePromoCode(chi.URLParam(r, "code"))
+	if err := promo.Validate(); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid promotion: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	existing, exists := s.promotions[promo.Code]
+	if !exists {
+		s.mu.Unlock()
+		http.Error(w, "Promotion not found", http.StatusNotFound)
+		return
+	}
+	promo.Redemptions = existing.Redemptions
+	promo.uses = existing.uses
+	s.promotions[promo.Code] = &promo
+	updated := promo
+	s.mu.Unlock()
+
+	slog.Info("promotion updated", "code", updated.Code, "kind", updated.Kind)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(updated); err != nil {
+		slog.Error("failed to encode promotion", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleDeletePromotion handles DELETE /promotions/{code} requests to retire
+// a promo code.
+func (s *Store) HandleDeletePromotion(w http.ResponseWriter, r *http.Request) {
+	code := normalizePromoCode(chi.URLParam(r, "code"))
+
+	s.mu.Lock()
+	_, exists := s.promotions[code]
+	delete(s.promotions, code)
+	s.mu.Unlock()
+
+	if !exists {
+		http.Error(w, "Promotion not found", http.StatusNotFound)
+		return
+	}
+	slog.Info("promotion deleted", "code", code)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RequireAdmin is a middleware that only lets through requests carrying the
+// admin token in an "Authorization: Bearer" header. Every request is refused
+// while no admin token is configured.
+func (s *Store) RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.isAdmin(bearerToken(r)) {
+			slog.Warn("admin request refused", "method", r.Method, "path", r.URL.Path)
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "Admin token required", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// bearerToken returns the token of a request's "Authorization: Bearer" header,
+// or "" if it has none.
+func bearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
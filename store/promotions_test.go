@@ -0,0 +1,317 @@
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// promotionsRouter returns a router with the order and promotion endpoints of the store.
+func promotionsRouter(store *Store) *chi.Mux {
+	router := chi.NewRouter()
+	router.Post("/order", store.HandleCreateOrder)
+	router.Post("/promotions", store.HandleCreatePromotion)
+	router.Get("/promotions", store.HandleGetPromotions)
+	router.Get("/promotions/{code}", store.HandleGetPromotion)
+	router.Put("/promotions/{code}", store.HandleUpdatePromotion)
+	router.Delete("/promotions/{code}", store.HandleDeletePromotion)
+	return router
+}
+
+// sendJSON sends a request with the JSON encoding of body to the router.
+func sendJSON(router http.Handler, method, path string, body any) *httptest.ResponseRecorder {
+	data, _ := json.Marshal(body)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(method, path, bytes.NewReader(data)))
+	return rec
+}
+
+// TestPromoCodesDiscountOrders verifies the discount of each kind of promo code and that it is
+// taken off the pizzas before tax.
+func TestPromoCodesDiscountOrders(t *testing.T) {
+	tests := []struct {
+		name     string
+		promo    Promotion
+		items    []OrderItem
+		discount int
+	}{
+		{
+			name:     "percentage",
+			promo:    Promotion{Code: "tenoff", Kind: PromoPercentage, Rate: 1000},
+			items:    []OrderItem{{PizzaType: "Pepperoni", Quantity: 2}},
+			discount: 300,
+		},
+		{
+			name:     "fixed amount",
+			promo:    Promotion{Code: "FIVE", Kind: PromoFixedAmount, Amount: 500},
+			items:    []OrderItem{{PizzaType: "Pepperoni", Quantity: 2}},
+			discount: 500,
+		},
+		{
+			name:     "fixed amount capped at subtotal",
+			promo:    Promotion{Code: "BIG", Kind: PromoFixedAmount, Amount: 5000},
+			items:    []OrderItem{{PizzaType: "Margherita", Quantity: 1}},
+			discount: 1000,
+		},
+		{
+			name:     "buy two get one",
+			promo:    Promotion{Code: "3FOR2", Kind: PromoBuyNGetOne, PizzaType: "Margherita", BuyQuantity: 2},
+			items:    []OrderItem{{PizzaType: "Margherita", Quantity: 7}, {PizzaType: "Pepperoni", Quantity: 1}},
+			discount: 2000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewStore()
+			router := promotionsRouter(store)
+			if rec := sendJSON(router, http.MethodPost, "/promotions", tt.promo); rec.Code != http.StatusCreated {
+				t.Fatalf("expected status 201 Created, got %d: %s", rec.Code, rec.Body.String())
+			}
+
+			rec := sendJSON(router, http.MethodPost, "/order", CreateOrderRequest{OrderItems: tt.items, PromoCode: tt.promo.Code})
+			if rec.Code != http.StatusCreated {
+				t.Fatalf("expected status 201 Created, got %d: %s", rec.Code, rec.Body.String())
+			}
+			var order Order
+			json.NewDecoder(rec.Body).Decode(&order)
+
+			pricing := order.Pricing
+			if pricing.Discount != tt.discount {
+				t.Errorf("expected discount %d, got %d", tt.discount, pricing.Discount)
+			}
+			if pricing.PromoCode != normalizePromoCode(tt.promo.Code) {
+				t.Errorf("expected promo code '%s', got '%s'", normalizePromoCode(tt.promo.Code), pricing.PromoCode)
+			}
+			if pricing.Taxes[0].Base != pricing.Subtotal-tt.discount {
+				t.Errorf("expected tax base %d, got %d", pricing.Subtotal-tt.discount, pricing.Taxes[0].Base)
+			}
+			if pricing.Total != pricing.Subtotal-tt.discount+pricing.TaxTotal {
+				t.Errorf("expected total %d, got %d", pricing.Subtotal-tt.discount+pricing.TaxTotal, pricing.Total)
+			}
+		})
+	}
+}
+
+// TestPromoCodesRejectedWhenNotApplicable verifies that POST /order returns 422 for promo codes
+// that are unknown, out of their validity window, below the minimum amount or without matching items.
+func TestPromoCodesRejectedWhenNotApplicable(t *testing.T) {
+	past := time.Now().Add(-48 * time.Hour)
+	yesterday := time.Now().Add(-24 * time.Hour)
+	tomorrow := time.Now().Add(24 * time.Hour)
+
+	store := NewStore()
+	router := promotionsRouter(store)
+	for _, promo := range []Promotion{
+		{Code: "EXPIRED", Kind: PromoPercentage, Rate: 1000, ValidFrom: &past, ValidUntil: &yesterday},
+		{Code: "SOON", Kind: PromoPercentage, Rate: 1000, ValidFrom: &tomorrow},
+		{Code: "BIGORDER", Kind: PromoFixedAmount, Amount: 500, MinOrderAmount: 3000},
+		{Code: "HAWAII", Kind: PromoBuyNGetOne, PizzaType: "Hawaiian", BuyQuantity: 1},
+	} {
+		if rec := sendJSON(router, http.MethodPost, "/promotions", promo); rec.Code != http.StatusCreated {
+			t.Fatalf("expected status 201 Created, got %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+
+	items := []OrderItem{{PizzaType: "Margherita", Quantity: 2}}
+	for _, code := range []string{"UNKNOWN", "EXPIRED", "SOON", "BIGORDER", "HAWAII"} {
+		rec := sendJSON(router, http.MethodPost, "/order", CreateOrderRequest{OrderItems: items, PromoCode: code})
+		if rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status 422 Unprocessable Entity for %s, got %d", code, rec.Code)
+		}
+	}
+	if len(store.orders) != 0 {
+		t.Errorf("expected no orders to be stored, got %d", len(store.orders))
+	}
+}
+
+// TestPromoCodeUsageLimitPerCustomer verifies that a customer cannot redeem a code more often than
+// allowed, while other customers still can.
+func TestPromoCodeUsageLimitPerCustomer(t *testing.T) {
+	store := NewStore()
+	router := promotionsRouter(store)
+	sendJSON(router, http.MethodPost, "/promotions", Promotion{Code: "WELCOME", Kind: PromoPercentage, Rate: 2000, MaxUsesPerCustomer: 1})
+
+	alice := addCustomer(store, "Alice")
+	bob := addCustomer(store, "Bob")
+
+	items := []OrderItem{{PizzaType: "Margherita", Quantity: 1}}
+	steps := []struct {
+		customerID *uuid.UUID
+		status     int
+	}{
+		{nil, http.StatusUnprocessableEntity},
+		{&alice, http.StatusCreated},
+		{&alice, http.StatusUnprocessableEntity},
+		{&bob, http.StatusCreated},
+	}
+	for i, step := range steps {
+		rec := sendJSON(router, http.MethodPost, "/order", CreateOrderRequest{OrderItems: items, PromoCode: "welcome", CustomerID: step.customerID})
+		if rec.Code != step.status {
+			t.Errorf("step %d: expected status %d, got %d", i, step.status, rec.Code)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/promotions/WELCOME", nil))
+	var promo Promotion
+	json.NewDecoder(rec.Body).Decode(&promo)
+	if promo.Redemptions != 2 {
+		t.Errorf("expected 2 redemptions, got %d", promo.Redemptions)
+	}
+}
+
+// TestPromotionAdminEndpoints verifies creating, listing, updating and deleting promo codes.
+func TestPromotionAdminEndpoints(t *testing.T) {
+	store := NewStore()
+	router := promotionsRouter(store)
+
+	if rec := sendJSON(router, http.MethodPost, "/promotions", Promotion{Code: "bad", Kind: "mystery"}); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 Bad Request for an unknown kind, got %d", rec.Code)
+	}
+	sendJSON(router, http.MethodPost, "/promotions", Promotion{Code: "B", Kind: PromoFixedAmount, Amount: 100})
+	sendJSON(router, http.MethodPost, "/promotions", Promotion{Code: "A", Kind: PromoFixedAmount, Amount: 200})
+	if rec := sendJSON(router, http.MethodPost, "/promotions", Promotion{Code: "a", Kind: PromoFixedAmount, Amount: 300}); rec.Code != http.StatusConflict {
+		t.Errorf("expected status 409 Conflict for a duplicate code, got %d", rec.Code)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/promotions", nil))
+	var promos []Promotion
+	json.NewDecoder(rec.Body).Decode(&promos)
+	if len(promos) != 2 || promos[0].Code != "A" || promos[1].Code != "B" {
+		t.Errorf("expected promotions A and B, got %+v", promos)
+	}
+
+	rec = sendJSON(router, http.MethodPut, "/promotions/a", Promotion{Kind: PromoPercentage, Rate: 1500})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200 OK, got %d", rec.Code)
+	}
+	var updated Promotion
+	json.NewDecoder(rec.Body).Decode(&updated)
+	if updated.Code != "A" || updated.Kind != PromoPercentage || updated.Rate != 1500 {
+		t.Errorf("expected A to become a 15%% promotion, got %+v", updated)
+	}
+	if rec := sendJSON(router, http.MethodPut, "/promotions/C", Promotion{Kind: PromoPercentage, Rate: 1500}); rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 Not Found when updating an unknown code, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/promotions/B", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("expected status 204 No Content, got %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/promotions/B", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 Not Found after deleting, got %d", rec.Code)
+	}
+}
+
+// TestPromotionAdminWhileRedeeming verifies that promo codes can be created and updated while
+// orders redeem them; run with -race to catch responses encoded from the stored promotion.
+func TestPromotionAdminWhileRedeeming(t *testing.T) {
+	store := NewStore()
+	router := promotionsRouter(store)
+	promo := Promotion{Code: "TENOFF", Kind: PromoPercentage, Rate: 1000}
+	items := []OrderItem{{PizzaType: "Margherita", Quantity: 1}}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		sendJSON(router, http.MethodPost, "/promotions", promo)
+		for range 100 {
+			sendJSON(router, http.MethodPut, "/promotions/TENOFF", promo)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for range 200 {
+			store.redeemPromotion(promo.Code, "", items)
+		}
+	}()
+	wg.Wait()
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/promotions/TENOFF", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status 200 OK, got %d", rec.Code)
+	}
+}
+
+// TestPromotionsRequireAdminToken verifies that promo codes can only be added, listed, looked
+// up, changed or retired with the admin token, and not at all while no admin token is configured.
+func TestPromotionsRequireAdminToken(t *testing.T) {
+	promo := Promotion{Code: "TENOFF", Kind: PromoPercentage, Rate: 1000}
+	send := func(router http.Handler, method, path, authorization string) int {
+		data, _ := json.Marshal(promo)
+		req := httptest.NewRequest(method, path, bytes.NewReader(data))
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	adminRouter := func(store *Store) *chi.Mux {
+		router := chi.NewRouter()
+		router.With(store.RequireAdmin).Post("/promotions", store.HandleCreatePromotion)
+		router.With(store.RequireAdmin).Get("/promotions", store.HandleGetPromotions)
+		router.With(store.RequireAdmin).Get("/promotions/{code}", store.HandleGetPromotion)
+		router.With(store.RequireAdmin).Put("/promotions/{code}", store.HandleUpdatePromotion)
+		router.With(store.RequireAdmin).Delete("/promotions/{code}", store.HandleDeletePromotion)
+		return router
+	}
+
+	unconfigured := NewStore()
+	if code := send(adminRouter(unconfigured), http.MethodPost, "/promotions", "Bearer "); code != http.StatusUnauthorized {
+		t.Errorf("expected status 401 without a configured admin token, got %d", code)
+	}
+
+	store := NewStore()
+	store.SetAdminToken("secret")
+	router := adminRouter(store)
+	for _, authorization := range []string{"", "Bearer wrong", "Basic secret", "secret"} {
+		if code := send(router, http.MethodPost, "/promotions", authorization); code != http.StatusUnauthorized {
+			t.Errorf("expected status 401 with Authorization %q, got %d", authorization, code)
+		}
+	}
+	if len(store.promotions) != 0 {
+		t.Fatalf("expected no promotion to be created, got %d", len(store.promotions))
+	}
+
+	if code := send(router, http.MethodPost, "/promotions", "Bearer secret"); code != http.StatusCreated {
+		t.Errorf("expected status 201 Created, got %d", code)
+	}
+	for _, path := range []string{"/promotions", "/promotions/TENOFF"} {
+		if code := send(router, http.MethodGet, path, ""); code != http.StatusUnauthorized {
+			t.Errorf("expected status 401 for GET %s without a token, got %d", path, code)
+		}
+		if code := send(router, http.MethodGet, path, "Bearer wrong"); code != http.StatusUnauthorized {
+			t.Errorf("expected status 401 for GET %s with a wrong token, got %d", path, code)
+		}
+		if code := send(router, http.MethodGet, path, "Bearer secret"); code != http.StatusOK {
+			t.Errorf("expected status 200 OK for GET %s with the token, got %d", path, code)
+		}
+	}
+	if code := send(router, http.MethodPut, "/promotions/TENOFF", "Bearer wrong"); code != http.StatusUnauthorized {
+		t.Errorf("expected status 401 for an update with a wrong token, got %d", code)
+	}
+	if code := send(router, http.MethodPut, "/promotions/TENOFF", "Bearer secret"); code != http.StatusOK {
+		t.Errorf("expected status 200 OK for an update with the token, got %d", code)
+	}
+	if code := send(router, http.MethodDelete, "/promotions/TENOFF", ""); code != http.StatusUnauthorized {
+		t.Errorf("expected status 401 for a delete without a token, got %d", code)
+	}
+	if code := send(router, http.MethodDelete, "/promotions/TENOFF", "Bearer secret"); code != http.StatusNoContent {
+		t.Errorf("expected status 204 No Content for a delete with the token, got %d", code)
+	}
+}
@@ -0,0 +1,178 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// Default estimates used to decide when a scheduled order is sent to the
+// kitchen so that it arrives at the scheduled time.
+const (
+	DefaultCookEstimate     = 30 * time.Second
+	DefaultDeliveryEstimate = 20 * time.Second
+)
+
+// MaxScheduleAhead is how far in the future an order can be scheduled.
+const MaxScheduleAhead = 7 * 24 * time.Hour
+
+// RescheduleRequest represents the request body for changing when a scheduled
+// order is delivered.
+type RescheduleRequest struct {
+	ScheduledFor time.Time `json:"scheduledFor"`
+}
+
+// SetScheduleEstimates sets how long the store expects the kitchen to cook an
+// order and, for orders without a delivery address, the delivery to take.
+// Scheduled orders are sent to the kitchen that long before their time.
+func (s *Store) SetScheduleEstimates(cook, delivery time.Duration) {
+	s.cookEstimate = cook
+	s.deliveryEstimate = delivery
+}
+
+// validScheduleTime reports whether an order can be scheduled for the time.
+func validScheduleTime(t time.Time, now time.Time) bool {
+	return t.After(now) && t.Before(now.Add(MaxScheduleAhead))
+}
+
+// scheduleDispatch holds a scheduled order until the time it has to be sent
+// to the kitchen: its scheduled time minus the lead time needed to cook and
+// deliver it. Orders whose lead time has already started are sent right away.
+// The caller must hold s.mu.
+func (s *Store) scheduleDispatch(order *Order, lead time.Duration) {
+	dispatchAt := order.ScheduledFor.Add(-lead)
+	order.DispatchAt = &dispatchAt
+
+	if timer, exists := s.schedules[order.OrderID]; exists {
+		timer.Stop()
+	}
+	orderID := order.OrderID
+	s.schedules[orderID] = time.AfterFunc(time.Until(dispatchAt), func() {
+		s.dispatchScheduled(orderID)
+	})
+	slog.Info("order scheduled", "orderId", orderID, "scheduledFor", order.ScheduledFor, "dispatchAt", dispatchAt)
+}
+
+// dispatchScheduled sends a scheduled order to the kitchen, unless it was
+// cancelled in the meantime.
+func (s *Store) dispatchScheduled(orderID uuid.UUID) {
+	s.mu.Lock()
+	order, exists := s.orders[orderID]
+	if !exists || order.OrderStatus != StatusScheduled {
+		s.mu.Unlock()
+		return
+	}
+	delete(s.schedules, orderID)
+	now := time.Now()
+	order.OrderStatus = "pending"
+	order.Timeline.DispatchedAt = &now
+	s.mu.Unlock()
+
+	slog.Info("scheduled order dispatched", "orderId", orderID)
+	s.recordStoreEvent(orderID, "pending", "")
+	s.callKitchenService(context.Background(), order)
+}
+
+// HandleRescheduleOrder handles PUT /order/{orderId}/schedule requests to
+// change when a scheduled order is delivered. Returns 409 Conflict once the
+// order has been sent to the kitchen.
+func (s *Store) HandleRescheduleOrder(w http.ResponseWriter, r *http.Request) {
+	orderID, err := uuid.Parse(chi.URLParam(r, "orderId"))
+	if err != nil {
+		http.Error(w, "Invalid orderId format", http.StatusBadRequest)
+		return
+	}
+
+	var req RescheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if !validScheduleTime(req.ScheduledFor, time.Now()) {
+		http.Error(w, "Invalid scheduledFor", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	order, exists := s.orders[orderID]
+	if !exists {
+		s.mu.Unlock()
+		http.Error(w, "Order not found", http.StatusNotFound)
+		return
+	}
+	if order.OrderStatus != StatusScheduled {
+		s.mu.Unlock()
+		http.Error(w, "Order is no longer scheduled", http.StatusConflict)
+		return
+	}
+
+	// Keep the lead time worked out when the order was placed
+	lead := order.ScheduledFor.Sub(*order.DispatchAt)
+	order.ScheduledFor = &req.ScheduledFor
+	s.scheduleDispatch(order, lead)
+	rescheduled := order.snapshot()
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(rescheduled); err != nil {
+		slog.Error("failed to encode order", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleCancelOrder handles DELETE /order/{orderId} requests to cancel a
+// scheduled order before it is sent to the kitchen. The payment is refunded
+// and the promo code redemption given back. Returns 409 Conflict once the
+// kitchen has the order.
+func (s *Store) HandleCancelOrder(w http.ResponseWriter, r *http.Request) {
+	orderID, err := uuid.Parse(chi.URLParam(r, "orderId"))
+	if err != nil {
+		http.Error(w, "Invalid orderId format", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	order, exists := s.orders[orderID]
+	if !exists {
+		s.mu.Unlock()
+		http.Error(w, "Order not found", http.StatusNotFound)
+		return
+	}
+	if order.OrderStatus != StatusScheduled {
+		s.mu.Unlock()
+		http.Error(w, "Order can no longer be cancelled", http.StatusConflict)
+		return
+	}
+	if timer, exists := s.schedules[orderID]; exists {
+		timer.Stop()
+		delete(s.schedules, orderID)
+	}
+	order.OrderStatus = StatusCancelled
+	cancelled := order.snapshot()
+	promoCode := order.Pricing.PromoCode
+	var customerKey string
+	if order.CustomerID != nil {
+		customerKey = order.CustomerID.String()
+	}
+	s.mu.Unlock()
+
+	slog.Info("order cancelled", "orderId", orderID)
+	s.recordStoreEvent(orderID, StatusCancelled, "")
+	if promoCode != "" {
+		s.releasePromotion(promoCode, customerKey)
+	}
+	go s.refundPayment(context.Background(), orderID)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(cancelled); err != nil {
+		slog.Error("failed to encode order", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,170 @@
+package store
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// scheduleSetup creates a store whose fake kitchen reports every cook request on the returned
+// channel, with estimates that send scheduled orders to the kitchen 200ms before their time.
+func scheduleSetup(t *testing.T) (*Store, *chi.Mux, chan CookRequest) {
+	t.Helper()
+	cookRequests := make(chan CookRequest, 10)
+	kitchenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req CookRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		w.WriteHeader(http.StatusAccepted)
+		cookRequests <- req
+	}))
+	t.Cleanup(kitchenServer.Close)
+
+	store := NewStore()
+	store.SetKitchenURL(kitchenServer.URL)
+	store.SetScheduleEstimates(100*time.Millisecond, 100*time.Millisecond)
+
+	router := chi.NewRouter()
+	router.Post("/order", store.HandleCreateOrder)
+	router.Delete("/order/{orderId}", store.HandleCancelOrder)
+	router.Put("/order/{orderId}/schedule", store.HandleRescheduleOrder)
+	return store, router, cookRequests
+}
+
+// scheduleOrder places an order of one Margherita scheduled for the given time.
+func scheduleOrder(t *testing.T, router http.Handler, scheduledFor time.Time) Order {
+	t.Helper()
+	rec := sendJSON(router, http.MethodPost, "/order", CreateOrderRequest{
+		OrderItems:   []OrderItem{{PizzaType: "Margherita", Quantity: 1}},
+		ScheduledFor: &scheduledFor,
+	})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status 201 Created, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var order Order
+	json.NewDecoder(rec.Body).Decode(&order)
+	return order
+}
+
+// TestScheduledOrderIsDispatchedBeforeItsTime verifies that a scheduled order is held until its
+// scheduled time minus the cook and delivery estimates, then sent to the kitchen.
+func TestScheduledOrderIsDispatchedBeforeItsTime(t *testing.T) {
+	store, router, cookRequests := scheduleSetup(t)
+
+	scheduledFor := time.Now().Add(500 * time.Millisecond)
+	order := scheduleOrder(t, router, scheduledFor)
+	if order.OrderStatus != StatusScheduled {
+		t.Errorf("expected status '%s', got '%s'", StatusScheduled, order.OrderStatus)
+	}
+	if order.DispatchAt == nil || !order.DispatchAt.Equal(scheduledFor.Add(-200*time.Millisecond)) {
+		t.Errorf("expected dispatch 200ms before the scheduled time, got %v", order.DispatchAt)
+	}
+
+	select {
+	case <-cookRequests:
+		t.Fatal("expected the order to be held until its dispatch time")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	select {
+	case req := <-cookRequests:
+		if req.OrderID != order.OrderID {
+			t.Errorf("expected order %s to be cooked, got %s", order.OrderID, req.OrderID)
+		}
+		if time.Now().Before(*order.DispatchAt) {
+			t.Error("expected the order to be dispatched at its dispatch time")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for the scheduled order to be dispatched")
+	}
+
+	got, _ := store.GetOrder(order.OrderID)
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	if got.OrderStatus == StatusScheduled || got.Timeline.DispatchedAt == nil {
+		t.Errorf("expected the order to be dispatched, got status '%s'", got.OrderStatus)
+	}
+}
+
+// TestCancelScheduledOrder verifies that a scheduled order can be cancelled before dispatch, is
+// never cooked and has its payment refunded, and that it cannot be cancelled twice.
+func TestCancelScheduledOrder(t *testing.T) {
+	store, router, cookRequests := scheduleSetup(t)
+	order := scheduleOrder(t, router, time.Now().Add(300*time.Millisecond))
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/order/"+order.OrderID.String(), nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200 OK, got %d", rec.Code)
+	}
+
+	select {
+	case <-cookRequests:
+		t.Fatal("expected a cancelled order not to be cooked")
+	case <-time.After(400 * time.Millisecond):
+	}
+	waitForPaymentStatus(t, store, order.OrderID, PaymentRefunded)
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/order/"+order.OrderID.String(), nil))
+	if rec.Code != http.StatusConflict {
+		t.Errorf("expected status 409 Conflict for a cancelled order, got %d", rec.Code)
+	}
+}
+
+// TestRescheduleOrder verifies that a scheduled order can be moved to a later time, keeping its
+// lead time, and that only scheduled orders can be rescheduled.
+func TestRescheduleOrder(t *testing.T) {
+	store, router, cookRequests := scheduleSetup(t)
+	order := scheduleOrder(t, router, time.Now().Add(300*time.Millisecond))
+
+	later := time.Now().Add(time.Hour)
+	rec := sendJSON(router, http.MethodPut, "/order/"+order.OrderID.String()+"/schedule", RescheduleRequest{ScheduledFor: later})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200 OK, got %d", rec.Code)
+	}
+	var rescheduled Order
+	json.NewDecoder(rec.Body).Decode(&rescheduled)
+	if !rescheduled.DispatchAt.Equal(later.Add(-200 * time.Millisecond)) {
+		t.Errorf("expected dispatch 200ms before the new time, got %v", rescheduled.DispatchAt)
+	}
+
+	select {
+	case <-cookRequests:
+		t.Fatal("expected the order to wait for its new time")
+	case <-time.After(400 * time.Millisecond):
+	}
+
+	if rec := sendJSON(router, http.MethodPut, "/order/"+order.OrderID.String()+"/schedule", RescheduleRequest{ScheduledFor: time.Now().Add(-time.Minute)}); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 Bad Request for a past time, got %d", rec.Code)
+	}
+
+	now := &Order{OrderID: uuid.New(), OrderStatus: "pending", CreatedAt: time.Now()}
+	store.mu.Lock()
+	store.orders[now.OrderID] = now
+	store.mu.Unlock()
+	if rec := sendJSON(router, http.MethodPut, "/order/"+now.OrderID.String()+"/schedule", RescheduleRequest{ScheduledFor: later}); rec.Code != http.StatusConflict {
+		t.Errorf("expected status 409 Conflict for an order that is not scheduled, got %d", rec.Code)
+	}
+}
+
+// TestPostOrderInvalidScheduledFor verifies that orders cannot be scheduled in the past.
+func TestPostOrderInvalidScheduledFor(t *testing.T) {
+	store, router, _ := scheduleSetup(t)
+
+	past := time.Now().Add(-time.Minute)
+	rec := sendJSON(router, http.MethodPost, "/order", CreateOrderRequest{
+		OrderItems:   []OrderItem{{PizzaType: "Margherita", Quantity: 1}},
+		ScheduledFor: &past,
+	})
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 Bad Request, got %d", rec.Code)
+	}
+	if len(store.orders) != 0 {
+		t.Errorf("expected no orders to be stored, got %d", len(store.orders))
+	}
+}
@@ -0,0 +1,218 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// Order stages timed by the store. An order is pending until the kitchen
+// starts cooking it (scheduled orders from the time they are dispatched), ready once cooked until a driver leaves with it, and in
+// delivery until it is delivered.
+const (
+	StagePending  = "pending"
+	StageCooking  = "cooking"
+	StageReady    = "ready"
+	StageDelivery = "delivery"
+)
+
+// StatusLate is broadcast to WebSocket clients when an order exceeds the SLA
+// of one of its stages.
+const StatusLate = "LATE"
+
+// DefaultSLACheckInterval is how often the SLA monitor looks for late orders.
+const DefaultSLACheckInterval = 5 * time.Second
+
+// OrderTimeline records when an order reached each stage and how many seconds
+// each completed stage took.
+type OrderTimeline struct {
+	DispatchedAt     *time.Time         `json:"dispatchedAt,omitempty"`
+	CookingStartedAt *time.Time         `json:"cookingStartedAt,omitempty"`
+	CookedAt         *time.Time         `json:"cookedAt,omitempty"`
+	OutForDeliveryAt *time.Time         `json:"outForDeliveryAt,omitempty"`
+	DeliveredAt      *time.Time         `json:"deliveredAt,omitempty"`
+	StageSeconds     map[string]float64 `json:"stageSeconds,omitempty"`
+}
+
+// DefaultSLAs returns the longest time an order should spend in each stage.
+func DefaultSLAs() map[string]time.Duration {
+	return map[string]time.Duration{
+		StagePending:  30 * time.Second,
+		StageCooking:  60 * time.Second,
+		StageReady:    30 * time.Second,
+		StageDelivery: 60 * time.Second,
+	}
+}
+
+// SetSLAs sets the longest time an order should spend in each stage. Stages
+// without an SLA are not monitored.
+func (s *Store) SetSLAs(slas map[string]time.Duration) {
+	s.slas = slas
+}
+
+// currentStage returns the stage the order is in and when it started, or
+// false if the order is delivered, will not be delivered, is still scheduled
+// or has no creation time. The caller must hold s.mu.
+func currentStage(order *Order) (string, time.Time, bool) {
+	timeline := order.Timeline
+	switch {
+	case timeline.DeliveredAt != nil, order.OrderStatus == "FAILED", order.OrderStatus == StatusRefundRequired,
+		order.OrderStatus == StatusScheduled, order.OrderStatus == StatusCancelled:
+		return "", time.Time{}, false
+	case timeline.OutForDeliveryAt != nil:
+		return StageDelivery, *timeline.OutForDeliveryAt, true
+	case timeline.CookedAt != nil:
+		return StageReady, *timeline.CookedAt, true
+	case timeline.CookingStartedAt != nil:
+		return StageCooking, *timeline.CookingStartedAt, true
+	case timeline.DispatchedAt != nil:
+		return StagePending, *timeline.DispatchedAt, true
+	case order.CreatedAt.IsZero():
+		return "", time.Time{}, false
+	default:
+		return StagePending, order.CreatedAt, true
+	}
+}
+
+// recordTimeline records the stage an event moves its order into and returns
+// the late notice to broadcast if the stage that just ended exceeded its SLA.
+func (s *Store) recordTimeline(event OrderEvent, status string, now time.Time) *OrderUpdate {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	order, exists := s.orders[event.OrderID]
+	if !exists {
+		return nil
+	}
+	stage, startedAt, active := currentStage(order)
+	timeline := &order.Timeline
+
+	switch {
+	case event.Source == "kitchen" && status != "COOKED" && status != "FAILED":
+		if timeline.CookingStartedAt != nil {
+			return nil
+		}
+		timeline.CookingStartedAt = &now
+	case status == "COOKED":
+		if timeline.CookedAt != nil {
+			return nil
+		}
+		timeline.CookedAt = &now
+	case status == "DELIVERED":
+		if timeline.OutForDeliveryAt == nil {
+			timeline.OutForDeliveryAt = &now
+		}
+		timeline.DeliveredAt = &now
+	case event.Source == "delivery" && status != StatusDeliveryFailed:
+		if timeline.OutForDeliveryAt != nil {
+			return nil
+		}
+		timeline.OutForDeliveryAt = &now
+	default:
+		return nil
+	}
+
+	if !active {
+		return nil
+	}
+	if timeline.StageSeconds == nil {
+		timeline.StageSeconds = make(map[string]float64)
+	}
+	took := now.Sub(startedAt)
+	timeline.StageSeconds[stage] = took.Seconds()
+	return s.flagLate(order, stage, took)
+}
+
+// flagLate marks the order late if it has spent longer than the SLA in the
+// stage, and returns the late notice to broadcast the first time the stage is
+// flagged. The caller must hold s.mu.
+func (s *Store) flagLate(order *Order, stage string, elapsed time.Duration) *OrderUpdate {
+	sla, ok := s.slas[stage]
+	if !ok || elapsed <= sla {
+		return nil
+	}
+	for _, late := range order.LateStages {
+		if late == stage {
+			return nil
+		}
+	}
+	order.Late = true
+	order.LateStages = append(order.LateStages, stage)
+	slog.Warn("order late", "orderId", order.OrderID, "stage", stage, "elapsed", elapsed.Round(time.Second), "sla", sla)
+	return &OrderUpdate{
+		OrderID: order.OrderID,
+		Status:  StatusLate,
+		Source:  "store",
+		Reason:  fmt.Sprintf("%s stage exceeded its %s SLA", stage, sla),
+	}
+}
+
+// broadcastLate records and broadcasts a late notice.
+func (s *Store) broadcastLate(update *OrderUpdate) {
+	s.trackEvent(OrderEvent{OrderID: update.OrderID, Status: update.Status, Source: update.Source, Reason: update.Reason})
+	s.BroadcastOrderUpdate(*update)
+}
+
+// checkSLAs flags the orders that have spent longer than the SLA in their
+// current stage.
+func (s *Store) checkSLAs(now time.Time) {
+	var late []*OrderUpdate
+	s.mu.Lock()
+	for _, order := range s.orders {
+		stage, startedAt, active := currentStage(order)
+		if !active {
+			continue
+		}
+		if update := s.flagLate(order, stage, now.Sub(startedAt)); update != nil {
+			late = append(late, update)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, update := range late {
+		s.broadcastLate(update)
+	}
+}
+
+// StartSLAMonitor checks for late orders every interval until the context is
+// done.
+func (s *Store) StartSLAMonitor(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				s.checkSLAs(now)
+			}
+		}
+	}()
+}
+
+// HandleGetLateOrders handles GET /orders/late requests. Returns the orders
+// flagged late that are still on their way to the customer, oldest first.
+func (s *Store) HandleGetLateOrders(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	orders := make([]*Order, 0)
+	for _, order := range s.orders {
+		if _, _, active := currentStage(order); order.Late && active {
+			orders = append(orders, order)
+		}
+	}
+	sort.Slice(orders, func(i, j int) bool { return orders[i].CreatedAt.Before(orders[j].CreatedAt) })
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(orders); err != nil {
+		slog.Error("failed to encode late orders", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
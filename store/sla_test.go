@@ -0,0 +1,123 @@
+package store
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// TestEventsRecordOrderTimeline verifies that kitchen and delivery events record when the order
+// reached each stage and how long each stage took.
+func TestEventsRecordOrderTimeline(t *testing.T) {
+	store := NewStore()
+	store.SetDeliveryURL("http://127.0.0.1:0")
+	router := chi.NewRouter()
+	router.Post("/events", store.HandleEvent)
+
+	order := &Order{OrderID: uuid.New(), OrderItems: []OrderItem{{PizzaType: "Margherita", Quantity: 1}}, OrderStatus: "pending", CreatedAt: time.Now()}
+	store.orders[order.OrderID] = order
+
+	for _, event := range []OrderEvent{
+		{OrderID: order.OrderID, Status: "prep 10%", Source: "kitchen"},
+		{OrderID: order.OrderID, Status: "baking 60%", Source: "kitchen"},
+		{OrderID: order.OrderID, Status: "DONE", Source: "kitchen"},
+		{OrderID: order.OrderID, Status: "delivering 20%", Source: "delivery"},
+		{OrderID: order.OrderID, Status: "DELIVERED", Source: "delivery"},
+	} {
+		postEvent(router, event)
+	}
+
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	timeline := order.Timeline
+	if timeline.CookingStartedAt == nil || timeline.CookedAt == nil || timeline.OutForDeliveryAt == nil || timeline.DeliveredAt == nil {
+		t.Fatalf("expected every stage to be timed, got %+v", timeline)
+	}
+	if !order.CreatedAt.Before(*timeline.CookingStartedAt) || timeline.DeliveredAt.Before(*timeline.OutForDeliveryAt) {
+		t.Errorf("expected stages in order, got %+v", timeline)
+	}
+	for _, stage := range []string{StagePending, StageCooking, StageReady, StageDelivery} {
+		if _, ok := timeline.StageSeconds[stage]; !ok {
+			t.Errorf("expected the duration of the %s stage, got %v", stage, timeline.StageSeconds)
+		}
+	}
+	if order.Late {
+		t.Errorf("expected the order to be on time, got late stages %v", order.LateStages)
+	}
+}
+
+// TestSLAMonitorFlagsLateOrders verifies that orders exceeding a stage SLA are flagged once, a
+// LATE update is broadcast and they are listed by GET /orders/late.
+func TestSLAMonitorFlagsLateOrders(t *testing.T) {
+	store := NewStore()
+	store.SetSLAs(map[string]time.Duration{StagePending: time.Minute, StageCooking: time.Minute})
+
+	now := time.Now()
+	cookingStarted := now.Add(-30 * time.Second)
+	late := &Order{OrderID: uuid.New(), OrderStatus: "pending", CreatedAt: now.Add(-2 * time.Minute)}
+	onTime := &Order{OrderID: uuid.New(), OrderStatus: "cooking", CreatedAt: now.Add(-5 * time.Minute), Timeline: OrderTimeline{CookingStartedAt: &cookingStarted}}
+	delivered := &Order{OrderID: uuid.New(), OrderStatus: "DELIVERED", CreatedAt: now.Add(-time.Hour), Timeline: OrderTimeline{DeliveredAt: &now}}
+	for _, order := range []*Order{late, onTime, delivered} {
+		store.orders[order.OrderID] = order
+	}
+
+	store.checkSLAs(now)
+	store.checkSLAs(now.Add(time.Second))
+
+	if !late.Late || len(late.LateStages) != 1 || late.LateStages[0] != StagePending {
+		t.Errorf("expected the pending order to be late once, got %v", late.LateStages)
+	}
+	if onTime.Late || delivered.Late {
+		t.Error("expected only the pending order to be late")
+	}
+	events := store.GetOrderEvents(late.OrderID)
+	if len(events) != 1 || events[0].Status != StatusLate || events[0].Source != "store" {
+		t.Errorf("expected one LATE store event, got %+v", events)
+	}
+
+	router := chi.NewRouter()
+	router.Get("/orders/late", store.HandleGetLateOrders)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/orders/late", nil))
+	var orders []Order
+	json.NewDecoder(rec.Body).Decode(&orders)
+	if len(orders) != 1 || orders[0].OrderID != late.OrderID {
+		t.Errorf("expected only the late order, got %+v", orders)
+	}
+}
+
+// TestSlowStageFlaggedWhenItEnds verifies that a stage that took longer than its SLA is flagged
+// when the event ending it arrives, even if the monitor did not catch it.
+func TestSlowStageFlaggedWhenItEnds(t *testing.T) {
+	store := NewStore()
+	store.SetSLAs(map[string]time.Duration{StageCooking: time.Minute})
+	router := chi.NewRouter()
+	router.Post("/events", store.HandleEvent)
+
+	cookingStarted := time.Now().Add(-2 * time.Minute)
+	order := &Order{OrderID: uuid.New(), OrderStatus: "baking 90%", CreatedAt: cookingStarted, Timeline: OrderTimeline{CookingStartedAt: &cookingStarted}}
+	store.orders[order.OrderID] = order
+
+	postEvent(router, OrderEvent{OrderID: order.OrderID, Status: "FAILED", Source: "kitchen", Reason: "burnt"})
+	if order.Late {
+		t.Fatal("expected a failed order not to be flagged late")
+	}
+
+	order.OrderStatus = "baking 90%"
+	store.SetDeliveryURL("http://127.0.0.1:0")
+	postEvent(router, OrderEvent{OrderID: order.OrderID, Status: "DONE", Source: "kitchen"})
+
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	if !order.Late || order.LateStages[0] != StageCooking {
+		t.Errorf("expected the cooking stage to be late, got %v", order.LateStages)
+	}
+	if seconds := order.Timeline.StageSeconds[StageCooking]; seconds < 120 {
+		t.Errorf("expected cooking to take at least 120 seconds, got %v", seconds)
+	}
+}
@@ -0,0 +1,142 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// streamSubscription builds the subscription of an order stream from its
+// orderId (repeated or comma separated), customerId and channel query
+// parameters, at least one of which is required, and the admin token of its
+// Authorization header. The token is never read from the query, which ends
+// up in request logs.
+func streamSubscription(r *http.Request) (SubscriptionMessage, *orderError) {
+	query := r.URL.Query()
+	msg := SubscriptionMessage{Action: ActionSubscribe, Channel: query.Get("channel"), Token: bearerToken(r)}
+
+	for _, value := range query["orderId"] {
+		for _, part := range strings.Split(value, ",") {
+			orderID, err := uuid.Parse(strings.TrimSpace(part))
+			if err != nil {
+				return msg, &orderError{http.StatusBadRequest, "Invalid orderId format"}
+			}
+			msg.OrderIDs = append(msg.OrderIDs, orderID)
+		}
+	}
+	if value := query.Get("customerId"); value != "" {
+		customerID, err := uuid.Parse(value)
+		if err != nil {
+			return msg, &orderError{http.StatusBadRequest, "Invalid customerId format"}
+		}
+		msg.CustomerID = &customerID
+	}
+	if msg.Channel != "" && msg.Channel != ChannelAll {
+		return msg, &orderError{http.StatusBadRequest, "Invalid channel"}
+	}
+	if len(msg.OrderIDs) == 0 && msg.CustomerID == nil && msg.Channel == "" {
+		return msg, &orderError{http.StatusBadRequest, "orderId, customerId or channel query parameter is required"}
+	}
+	return msg, nil
+}
+
+// HandleOrderStream handles GET /orders/stream requests, streaming the same
+// order updates as the WebSocket as Server-Sent Events for clients that
+// cannot upgrade the connection. The orders streamed are chosen like a
+// WebSocket subscription, with orderId, customerId or channel=all query
+// parameters and, for the admin channel, the admin token in an
+// "Authorization: Bearer" header. Each event carries its ID, and clients
+// resuming with a Last-Event-ID header, or a lastEventId query parameter,
+// first get the events they missed. Streams that fall behind are closed.
+func (s *Store) HandleOrderStream(w http.ResponseWriter, r *http.Request) {
+	msg, orderErr := streamSubscription(r)
+	if orderErr != nil {
+		writeOrderError(w, orderErr)
+		return
+	}
+	if msg.Channel == ChannelAll && !s.isAdmin(msg.Token) {
+		http.Error(w, "Invalid admin token", http.StatusForbidden)
+		return
+	}
+
+	var lastEventID *uint64
+	value := r.Header.Get("Last-Event-ID")
+	if value == "" {
+		value = r.URL.Query().Get("lastEventId")
+	}
+	if value != "" {
+		id, err := s.hub.parseLastEventID(value)
+		if err != nil {
+			http.Error(w, "Invalid Last-Event-ID", http.StatusBadRequest)
+			return
+		}
+		lastEventID = &id
+	}
+
+	// Subscribe before responding, so no event is missed once the stream is open
+	clientID := "sse-" + uuid.NewString()
+	client := s.hub.addClient(clientID, nil, lastEventID)
+	defer s.hub.removeClient(clientID, client)
+	s.hub.Subscribe(clientID, msg)
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		slog.Error("order stream not supported", "error", err)
+		return
+	}
+	slog.Info("order stream opened", "clientId", clientID, "orders", len(msg.OrderIDs), "customerId", msg.CustomerID, "channel", msg.Channel, "lastEventId", value)
+	defer slog.Info("order stream closed", "clientId", clientID)
+
+	ticker := time.NewTicker(s.hub.pingPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case batch, ok := <-client.send:
+			if !ok {
+				return
+			}
+			if err := s.writeStream(rc, w, batch); err != nil {
+				slog.Warn("order stream write error", "clientId", clientID, "error", err)
+				return
+			}
+		case <-ticker.C:
+			// A comment line keeps proxies from closing an idle stream
+			if err := s.writeStreamFrame(rc, w, ": ping\n\n"); err != nil {
+				slog.Warn("order stream write error", "clientId", clientID, "error", err)
+				return
+			}
+		}
+	}
+}
+
+// writeStream writes a batch of events to an order stream as Server-Sent
+// Events named by their streams and IDs, which clients resume from.
+func (s *Store) writeStream(rc *http.ResponseController, w http.ResponseWriter, batch []hubEvent) error {
+	var frames strings.Builder
+	for _, queued := range batch {
+		fmt.Fprintf(&frames, "id: %s:%d\ndata: %s\n\n", queued.event.Stream, queued.event.ID, queued.message)
+	}
+	return s.writeStreamFrame(rc, w, frames.String())
+}
+
+// writeStreamFrame writes to an order stream and flushes it, giving up after
+// the hub's write wait where the connection supports write deadlines.
+func (s *Store) writeStreamFrame(rc *http.ResponseController, w http.ResponseWriter, frame string) error {
+	if err := rc.SetWriteDeadline(time.Now().Add(s.hub.writeWait)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	if _, err := w.Write([]byte(frame)); err != nil {
+		return err
+	}
+	return rc.Flush()
+}
@@ -0,0 +1,205 @@
+package store
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// streamSetup creates a store with a server for posting events and streaming order updates.
+func streamSetup(t *testing.T) (*Store, *chi.Mux, *httptest.Server) {
+	t.Helper()
+	store := NewStore()
+	router := chi.NewRouter()
+	router.Post("/events", store.HandleEvent)
+	router.Get("/orders/stream", store.HandleOrderStream)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return store, router, server
+}
+
+// openStream opens an order stream with the given query and Last-Event-ID, if any.
+func openStream(t *testing.T, server *httptest.Server, query string, lastEventID string) *bufio.Reader {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/orders/stream?"+query, nil)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("failed to open stream: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200 OK, got %d", resp.StatusCode)
+	}
+	if contentType := resp.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Errorf("expected an event stream, got '%s'", contentType)
+	}
+	return bufio.NewReader(resp.Body)
+}
+
+// readStreamEvent reads the next event of a stream, skipping comments, and checks that its ID
+// matches the event's stream and ID.
+func readStreamEvent(t *testing.T, stream *bufio.Reader) WebSocketEvent {
+	t.Helper()
+	var id string
+	var event WebSocketEvent
+	for {
+		line, err := stream.ReadString('\n')
+		if err != nil {
+			t.Fatalf("failed to read stream: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case strings.HasPrefix(line, "id: "):
+			id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "data: "):
+			json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event)
+		case line == "" && id != "":
+			if want := event.Stream + ":" + strconv.FormatUint(event.ID, 10); id != want {
+				t.Errorf("expected the stream ID to be the event's %s, got %s", want, id)
+			}
+			return event
+		}
+	}
+}
+
+// TestOrderStreamFiltersAndResumes verifies that the stream only sends the updates of the
+// requested orders or customer, and that a stream resumed with Last-Event-ID first gets the
+// updates it missed.
+func TestOrderStreamFiltersAndResumes(t *testing.T) {
+	store, router, server := streamSetup(t)
+
+	alice := uuid.New()
+	mine := &Order{OrderID: uuid.New(), OrderStatus: "pending"}
+	hers := &Order{OrderID: uuid.New(), OrderStatus: "pending", CustomerID: &alice}
+	store.orders[mine.OrderID] = mine
+	store.orders[hers.OrderID] = hers
+
+	orderStream := openStream(t, server, "orderId="+mine.OrderID.String(), "")
+	customerStream := openStream(t, server, "customerId="+alice.String(), "")
+	postEvent(router, OrderEvent{OrderID: hers.OrderID, Status: "prep 10%", Source: "kitchen"})
+	postEvent(router, OrderEvent{OrderID: mine.OrderID, Status: "prep 50%", Source: "kitchen"})
+
+	first := readStreamEvent(t, orderStream)
+	if first.OrderID != mine.OrderID || first.Status != "prep 50%" {
+		t.Errorf("expected the requested order's update, got %+v", first)
+	}
+	if got := readStreamEvent(t, customerStream); got.OrderID != hers.OrderID {
+		t.Errorf("expected the customer's order update, got %+v", got)
+	}
+
+	postEvent(router, OrderEvent{OrderID: mine.OrderID, Status: "DONE", Source: "kitchen"})
+	resumed := openStream(t, server, "orderId="+mine.OrderID.String(), first.Stream+":"+strconv.FormatUint(first.ID, 10))
+	postEvent(router, OrderEvent{OrderID: mine.OrderID, Status: "DELIVERED", Source: "delivery"})
+
+	for _, want := range []string{"COOKED", "DELIVERED"} {
+		if got := readStreamEvent(t, resumed); got.Status != want {
+			t.Errorf("expected '%s' after resuming, got '%s'", want, got.Status)
+		}
+	}
+}
+
+// TestOrderStreamResumesAfterRestart verifies that a stream resumed with the ID of an event
+// numbered before the store restarted gets every buffered update, even though the restarted
+// store numbers its events from 1 again.
+func TestOrderStreamResumesAfterRestart(t *testing.T) {
+	store, router, server := streamSetup(t)
+	order := &Order{OrderID: uuid.New(), OrderStatus: "pending"}
+	store.orders[order.OrderID] = order
+	postEvent(router, OrderEvent{OrderID: order.OrderID, Status: "prep 10%", Source: "kitchen"})
+	postEvent(router, OrderEvent{OrderID: order.OrderID, Status: "prep 50%", Source: "kitchen"})
+
+	for _, lastEventID := range []string{uuid.NewString() + ":1", "7"} {
+		stream := openStream(t, server, "orderId="+order.OrderID.String(), lastEventID)
+		for _, want := range []string{"prep 10%", "prep 50%"} {
+			got := readStreamEvent(t, stream)
+			if got.Status != want {
+				t.Errorf("expected '%s' after resuming from %s, got '%s'", want, lastEventID, got.Status)
+			}
+			if got.Stream != store.hub.stream {
+				t.Errorf("expected the restarted store's stream %s, got %s", store.hub.stream, got.Stream)
+			}
+		}
+	}
+}
+
+// TestOrderStreamAdminChannel verifies that a stream with the admin token in its Authorization
+// header gets the updates of every order.
+func TestOrderStreamAdminChannel(t *testing.T) {
+	store, router, server := streamSetup(t)
+	store.SetAdminToken("secret")
+	order := &Order{OrderID: uuid.New(), OrderStatus: "pending"}
+	store.orders[order.OrderID] = order
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/orders/stream?channel=all", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("failed to open stream: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200 OK, got %d", resp.StatusCode)
+	}
+
+	postEvent(router, OrderEvent{OrderID: order.OrderID, Status: "prep 10%", Source: "kitchen"})
+	if got := readStreamEvent(t, bufio.NewReader(resp.Body)); got.OrderID != order.OrderID {
+		t.Errorf("expected the order's update on the admin channel, got %+v", got)
+	}
+}
+
+// TestOrderStreamInvalidRequests verifies that streams without a filter, with invalid filters or
+// Last-Event-ID, or for every order without the admin token in the Authorization header are
+// rejected.
+func TestOrderStreamInvalidRequests(t *testing.T) {
+	store, router, _ := streamSetup(t)
+	store.SetAdminToken("secret")
+
+	tests := []struct {
+		name          string
+		query         string
+		lastEventID   string
+		authorization string
+		status        int
+	}{
+		{"no filter", "", "", "", http.StatusBadRequest},
+		{"invalid order", "orderId=not-a-uuid", "", "", http.StatusBadRequest},
+		{"invalid customer", "customerId=not-a-uuid", "", "", http.StatusBadRequest},
+		{"unknown channel", "channel=kitchen", "", "", http.StatusBadRequest},
+		{"invalid Last-Event-ID", "orderId=" + uuid.New().String(), "latest", "", http.StatusBadRequest},
+		{"admin channel without token", "channel=all", "", "", http.StatusForbidden},
+		{"admin channel with wrong token", "channel=all", "", "Bearer guess", http.StatusForbidden},
+		{"admin token in the query", "channel=all&token=secret", "", "", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/orders/stream?"+tt.query, nil)
+			if tt.lastEventID != "" {
+				req.Header.Set("Last-Event-ID", tt.lastEventID)
+			}
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Errorf("expected status %d, got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+		})
+	}
+	if len(store.hub.clients) != 0 {
+		t.Errorf("expected rejected streams not to be registered, got %d", len(store.hub.clients))
+	}
+}
@@ -1,9 +1,12 @@
 package store
 
 import (
+	"crypto/subtle"
 	"encoding/json"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -13,44 +16,198 @@ import (
 
 // OrderUpdate represents an order status update sent to WebSocket clients.
 type OrderUpdate struct {
-	OrderID uuid.UUID `json:"orderId"`
-	Status  string    `json:"status"`
-	Source  string    `json:"source"`
+	OrderID  uuid.UUID `json:"orderId"`
+	Status   string    `json:"status"`
+	Source   string    `json:"source"`
+	DriverID string    `json:"driverId,omitempty"`
+	Location *Location `json:"location,omitempty"`
+	Reason   string    `json:"reason,omitempty"`
+
+	EstimatedReadyAt    *time.Time `json:"estimatedReadyAt,omitempty"`
+	EstimatedDeliveryAt *time.Time `json:"estimatedDeliveryAt,omitempty"`
 }
 
 // WebSocketEvent represents the event format sent to frontend clients via WebSocket.
+// Events are numbered in the order they are broadcast, so reconnecting
+// clients can ask for the ones they missed. The numbers start again at 1 when
+// the store restarts, so events also carry the stream of the store process
+// that numbered them. Delivery updates include the
+// driver's location when it is known, and kitchen and delivery updates the
+// order's revised estimated times.
 type WebSocketEvent struct {
-	OrderID   uuid.UUID `json:"orderId"`
-	Status    string    `json:"status"`
-	Source    string    `json:"source"`
-	Timestamp string    `json:"timestamp"`
+	ID         uint64     `json:"id"`
+	Stream     string     `json:"stream"`
+	OrderID    uuid.UUID  `json:"orderId"`
+	CustomerID *uuid.UUID `json:"customerId,omitempty"`
+	Status     string     `json:"status"`
+	Source     string     `json:"source"`
+	DriverID   string     `json:"driverId,omitempty"`
+	Location   *Location  `json:"location,omitempty"`
+	Reason     string     `json:"reason,omitempty"`
+	Timestamp  string     `json:"timestamp"`
+
+	EstimatedReadyAt    *time.Time `json:"estimatedReadyAt,omitempty"`
+	EstimatedDeliveryAt *time.Time `json:"estimatedDeliveryAt,omitempty"`
+}
+
+// Client message actions and the admin channel of every order's updates.
+const (
+	ActionSubscribe   = "subscribe"
+	ActionUnsubscribe = "unsubscribe"
+	ChannelAll        = "all"
+)
+
+// SubscriptionMessage is sent by WebSocket clients to choose the order updates
+// they receive: the updates of specific orders, of every order of a customer,
+// or of every order on the admin "all" channel, which requires the admin token
+// when one is set. Clients receive nothing until they subscribe.
+type SubscriptionMessage struct {
+	Action     string      `json:"action"`
+	OrderIDs   []uuid.UUID `json:"orderIds,omitempty"`
+	CustomerID *uuid.UUID  `json:"customerId,omitempty"`
+	Channel    string      `json:"channel,omitempty"`
+	Token      string      `json:"token,omitempty"`
+}
+
+// Defaults for WebSocket clients: how many recent events are kept to replay
+// to reconnecting clients, how many messages may be queued for a client
+// before it is evicted as a slow consumer, how long a write may take, how
+// long to wait for a pong before the connection is considered dead, and how
+// often clients are pinged, which must be less than the pong wait.
+const (
+	DefaultWSReplayBuffer = 1000
+	DefaultWSSendBuffer   = 64
+	DefaultWSWriteWait    = 10 * time.Second
+	DefaultWSPongWait     = 60 * time.Second
+	DefaultWSPingPeriod   = DefaultWSPongWait * 9 / 10
+)
+
+// hubEvent is a broadcast event and its JSON encoding.
+type hubEvent struct {
+	event   WebSocketEvent
+	message []byte
+}
+
+// wsClient is a connected WebSocket client and the updates it subscribed to.
+// Events are queued on send, live events one at a time and replayed events
+// as a single batch, and written by the client's write pump, the only
+// goroutine writing to conn. The subscriptions are guarded by
+// WebSocketHub.mu, and send is closed by the hub when the client is removed.
+//
+// A client that reconnected with the ID of the last event it received gets
+// the events it missed since then replayed as it subscribes to them.
+type wsClient struct {
+	conn      *websocket.Conn
+	send      chan []hubEvent
+	orders    map[uuid.UUID]bool
+	customers map[uuid.UUID]bool
+	all       bool
+
+	replay      bool
+	lastEventID uint64
+}
+
+// newWSClient creates a client with no subscriptions.
+func newWSClient(conn *websocket.Conn, sendBuffer int) *wsClient {
+	return &wsClient{
+		conn:      conn,
+		send:      make(chan []hubEvent, sendBuffer),
+		orders:    make(map[uuid.UUID]bool),
+		customers: make(map[uuid.UUID]bool),
+	}
 }
 
-// WebSocketHub manages WebSocket client connections and broadcasts messages.
+// wants reports whether the client subscribed to the event's order.
+func (c *wsClient) wants(event WebSocketEvent) bool {
+	if c.all || c.orders[event.OrderID] {
+		return true
+	}
+	return event.CustomerID != nil && c.customers[*event.CustomerID]
+}
+
+// WebSocketHub manages WebSocket client connections and their subscriptions,
+// and routes events to the clients subscribed to them.
 type WebSocketHub struct {
 	mu      sync.RWMutex
-	clients map[string]*websocket.Conn
+	clients map[string]*wsClient
+
+	// stream identifies the hub's numbering of events, lastEventID numbers
+	// broadcast events, and history keeps the most recent of them, the
+	// event numbered n at index (n-1) % len(history).
+	stream      string
+	lastEventID uint64
+	history     []hubEvent
+
+	sendBuffer int
+	writeWait  time.Duration
+	pongWait   time.Duration
+	pingPeriod time.Duration
 }
 
 // NewWebSocketHub creates a new WebSocketHub instance.
 func NewWebSocketHub() *WebSocketHub {
 	return &WebSocketHub{
-		clients: make(map[string]*websocket.Conn),
+		clients:    make(map[string]*wsClient),
+		stream:     uuid.NewString(),
+		history:    make([]hubEvent, DefaultWSReplayBuffer),
+		sendBuffer: DefaultWSSendBuffer,
+		writeWait:  DefaultWSWriteWait,
+		pongWait:   DefaultWSPongWait,
+		pingPeriod: DefaultWSPingPeriod,
 	}
 }
 
-// AddClient registers a new WebSocket client connection with a client ID.
+// AddClient registers a new WebSocket client connection with a client ID and
+// starts its write pump. The client has no subscriptions. A client already
+// registered with the same ID is replaced and disconnected.
 func (h *WebSocketHub) AddClient(clientID string, conn *websocket.Conn) {
+	client := h.addClient(clientID, conn, nil)
+	go h.writePump(clientID, client)
+}
+
+// addClient registers a client without starting anything to write its
+// queued events, and returns it. A client reconnecting with the ID of the
+// last event it received gets the events it missed replayed as it
+// subscribes.
+func (h *WebSocketHub) addClient(clientID string, conn *websocket.Conn, lastEventID *uint64) *wsClient {
+	client := newWSClient(conn, h.sendBuffer)
+	if lastEventID != nil {
+		client.replay = true
+		client.lastEventID = *lastEventID
+	}
+
 	h.mu.Lock()
-	defer h.mu.Unlock()
-	h.clients[clientID] = conn
+	if previous, exists := h.clients[clientID]; exists {
+		close(previous.send)
+	}
+	h.clients[clientID] = client
+	h.mu.Unlock()
+	return client
 }
 
-// RemoveClient unregisters a WebSocket client connection by client ID.
+// RemoveClient unregisters a WebSocket client connection by client ID. Its
+// write pump closes the connection once queued messages are written.
 func (h *WebSocketHub) RemoveClient(clientID string) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
+	if client, exists := h.clients[clientID]; exists {
+		close(client.send)
+		delete(h.clients, clientID)
+	}
+}
+
+// removeClient unregisters a client if it is still registered under its ID,
+// leaving alone a client that has since connected with the same ID. It
+// reports whether the client was removed.
+func (h *WebSocketHub) removeClient(clientID string, client *wsClient) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.clients[clientID] != client {
+		return false
+	}
+	close(client.send)
 	delete(h.clients, clientID)
+	return true
 }
 
 // HasClient checks if a client with the given ID is registered.
@@ -61,14 +218,182 @@ func (h *WebSocketHub) HasClient(clientID string) bool {
 	return exists
 }
 
-// Broadcast sends a message to all connected WebSocket clients.
-func (h *WebSocketHub) Broadcast(message []byte) {
+// Subscribe adds the orders, customer and channel of the message to the
+// client's subscriptions, or removes them for an unsubscribe message. A
+// reconnected client first gets the buffered events it missed that it is
+// subscribing to, ahead of any live event.
+func (h *WebSocketHub) Subscribe(clientID string, msg SubscriptionMessage) {
+	h.mu.Lock()
+	client, exists := h.clients[clientID]
+	if !exists {
+		h.mu.Unlock()
+		return
+	}
+	overflow := false
+	if msg.Action == ActionSubscribe && client.replay {
+		overflow = !h.replay(clientID, client, msg)
+	}
+	client.apply(msg)
+	h.mu.Unlock()
+
+	if overflow && h.removeClient(clientID, client) {
+		slog.Warn("evicted slow websocket client", "clientId", clientID, "queued", h.sendBuffer)
+	}
+}
+
+// replay queues the buffered events since the client's last event that the
+// subscription adds, as a single batch. It reports false if the client's
+// queue is full. The caller must hold h.mu.
+func (h *WebSocketHub) replay(clientID string, client *wsClient, msg SubscriptionMessage) bool {
+	added := newWSClient(nil, 0)
+	added.apply(msg)
+
+	first := client.lastEventID + 1
+	if oldest := h.oldestEventID(); first < oldest {
+		slog.Warn("websocket events no longer buffered", "clientId", clientID, "lastEventId", client.lastEventID, "oldestEventId", oldest)
+		first = oldest
+	}
+	var batch []hubEvent
+	for id := first; id <= h.lastEventID; id++ {
+		buffered := h.history[(id-1)%uint64(len(h.history))]
+		if added.wants(buffered.event) && !client.wants(buffered.event) {
+			batch = append(batch, buffered)
+		}
+	}
+	if len(batch) == 0 {
+		return true
+	}
+
+	select {
+	case client.send <- batch:
+		slog.Info("replaying websocket events", "clientId", clientID, "events", len(batch))
+		return true
+	default:
+		return false
+	}
+}
+
+// parseLastEventID reads the ID of the last event a reconnecting client
+// received, given as "<stream>:<id>", or as "<id>" alone. An ID from another
+// stream, or beyond the last event of this one, was numbered before the store
+// restarted, so every buffered event is new to the client and 0 is returned.
+func (h *WebSocketHub) parseLastEventID(value string) (uint64, error) {
+	stream, number, found := strings.Cut(value, ":")
+	if !found {
+		stream, number = h.stream, value
+	}
+	id, err := strconv.ParseUint(number, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+
 	h.mu.RLock()
 	defer h.mu.RUnlock()
-	for clientID, conn := range h.clients {
-		err := conn.WriteMessage(websocket.TextMessage, message)
-		if err != nil {
-			slog.Error("websocket write error", "clientId", clientID, "error", err)
+	if stream != h.stream || id > h.lastEventID {
+		return 0, nil
+	}
+	return id, nil
+}
+
+// oldestEventID returns the ID of the oldest buffered event. The caller must
+// hold h.mu.
+func (h *WebSocketHub) oldestEventID() uint64 {
+	if size := uint64(len(h.history)); h.lastEventID > size {
+		return h.lastEventID - size + 1
+	}
+	return 1
+}
+
+// apply adds the orders, customer and channel of the message to the client's
+// subscriptions, or removes them for an unsubscribe message.
+func (c *wsClient) apply(msg SubscriptionMessage) {
+	subscribe := msg.Action == ActionSubscribe
+	for _, orderID := range msg.OrderIDs {
+		if subscribe {
+			c.orders[orderID] = true
+		} else {
+			delete(c.orders, orderID)
+		}
+	}
+	if msg.CustomerID != nil {
+		if subscribe {
+			c.customers[*msg.CustomerID] = true
+		} else {
+			delete(c.customers, *msg.CustomerID)
+		}
+	}
+	if msg.Channel == ChannelAll {
+		c.all = subscribe
+	}
+}
+
+// Broadcast numbers an event, keeps it for replay and queues it for the
+// WebSocket clients subscribed to its order without waiting for it to be
+// written. Clients whose queue is full are not keeping up and are evicted.
+func (h *WebSocketHub) Broadcast(event WebSocketEvent) {
+	slow := make(map[string]*wsClient)
+	h.mu.Lock()
+	event.ID = h.lastEventID + 1
+	event.Stream = h.stream
+	message, err := json.Marshal(event)
+	if err != nil {
+		h.mu.Unlock()
+		slog.Error("failed to marshal websocket event", "error", err)
+		return
+	}
+	h.lastEventID = event.ID
+	buffered := hubEvent{event: event, message: message}
+	h.history[(event.ID-1)%uint64(len(h.history))] = buffered
+
+	for clientID, client := range h.clients {
+		if !client.wants(event) {
+			continue
+		}
+		select {
+		case client.send <- []hubEvent{buffered}:
+		default:
+			slow[clientID] = client
+		}
+	}
+	h.mu.Unlock()
+
+	for clientID, client := range slow {
+		if h.removeClient(clientID, client) {
+			slog.Warn("evicted slow websocket client", "clientId", clientID, "queued", h.sendBuffer)
+		}
+	}
+}
+
+// writePump writes the messages queued for a client to its connection and
+// pings it to keep the connection alive. It closes the connection when the
+// client is removed or a write fails, which also ends the client's read loop.
+func (h *WebSocketHub) writePump(clientID string, client *wsClient) {
+	ticker := time.NewTicker(h.pingPeriod)
+	defer func() {
+		ticker.Stop()
+		client.conn.Close()
+	}()
+
+	for {
+		select {
+		case batch, ok := <-client.send:
+			client.conn.SetWriteDeadline(time.Now().Add(h.writeWait))
+			if !ok {
+				client.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""))
+				return
+			}
+			for _, queued := range batch {
+				if err := client.conn.WriteMessage(websocket.TextMessage, queued.message); err != nil {
+					slog.Error("websocket write error", "clientId", clientID, "error", err)
+					return
+				}
+			}
+		case <-ticker.C:
+			client.conn.SetWriteDeadline(time.Now().Add(h.writeWait))
+			if err := client.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				slog.Warn("websocket ping failed", "clientId", clientID, "error", err)
+				return
+			}
 		}
 	}
 }
@@ -80,8 +405,13 @@ var upgrader = websocket.Upgrader{
 }
 
 // HandleWebSocket handles WebSocket connection requests from frontend clients.
-// It upgrades the HTTP connection to WebSocket and registers the client
-// to receive order updates. Requires a clientId query parameter.
+// It upgrades the HTTP connection to WebSocket and registers the client,
+// which then sends SubscriptionMessages to choose the order updates it
+// receives. Clients that do not answer pings within the pong wait are
+// disconnected. Requires a clientId query parameter; clients reconnecting
+// pass the stream and ID of the last event they received as
+// lastEventId=<stream>:<id>, or 0 for none, to get the events they missed
+// replayed as they subscribe.
 func (s *Store) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 	clientID := r.URL.Query().Get("clientId")
 	if clientID == "" {
@@ -89,45 +419,103 @@ func (s *Store) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var lastEventID *uint64
+	if value := r.URL.Query().Get("lastEventId"); value != "" {
+		id, err := s.hub.parseLastEventID(value)
+		if err != nil {
+			http.Error(w, "Invalid lastEventId", http.StatusBadRequest)
+			return
+		}
+		lastEventID = &id
+	}
+
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		slog.Error("websocket upgrade error", "error", err)
 		return
 	}
 
-	s.hub.AddClient(clientID, conn)
-	slog.Info("websocket client connected", "clientId", clientID)
+	client := s.hub.addClient(clientID, conn, lastEventID)
+	go s.hub.writePump(clientID, client)
+	slog.Info("websocket client connected", "clientId", clientID, "lastEventId", r.URL.Query().Get("lastEventId"))
 
-	// Keep connection open and handle disconnection
+	// Read subscriptions until the client disconnects or stops answering pings
 	go func() {
 		defer func() {
-			s.hub.RemoveClient(clientID)
+			s.hub.removeClient(clientID, client)
 			conn.Close()
 			slog.Info("websocket client disconnected", "clientId", clientID)
 		}()
 
+		conn.SetReadDeadline(time.Now().Add(s.hub.pongWait))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(s.hub.pongWait))
+		})
 		for {
-			_, _, err := conn.ReadMessage()
+			_, data, err := conn.ReadMessage()
 			if err != nil {
 				break
 			}
+			var msg SubscriptionMessage
+			if err := json.Unmarshal(data, &msg); err != nil {
+				slog.Warn("invalid websocket message", "clientId", clientID, "error", err)
+				continue
+			}
+			s.handleSubscription(clientID, msg)
 		}
 	}()
 }
 
-// BroadcastOrderUpdate sends an order update to all connected WebSocket clients
-// using the WebSocketEvent format.
+// handleSubscription applies a client's subscribe or unsubscribe message.
+// Subscriptions to the admin channel are refused without the admin token.
+func (s *Store) handleSubscription(clientID string, msg SubscriptionMessage) {
+	if msg.Action != ActionSubscribe && msg.Action != ActionUnsubscribe {
+		slog.Warn("unknown websocket action", "clientId", clientID, "action", msg.Action)
+		return
+	}
+	if msg.Action == ActionSubscribe && msg.Channel == ChannelAll && !s.isAdmin(msg.Token) {
+		slog.Warn("websocket admin subscription refused", "clientId", clientID)
+		msg.Channel = ""
+	}
+	s.hub.Subscribe(clientID, msg)
+	slog.Info("websocket subscriptions changed", "clientId", clientID, "action", msg.Action, "orders", len(msg.OrderIDs), "customerId", msg.CustomerID, "channel", msg.Channel)
+}
+
+// isAdmin reports whether a token is the admin token. Every token is refused
+// while no admin token is configured.
+func (s *Store) isAdmin(token string) bool {
+	return s.adminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.adminToken)) == 1
+}
+
+// SetAdminToken sets the token of the admin endpoints, which WebSocket
+// clients must also send to subscribe to the updates of every order. Without
+// a token the admin endpoints and channel are closed to every client.
+func (s *Store) SetAdminToken(token string) {
+	s.adminToken = token
+}
+
+// BroadcastOrderUpdate sends an order update to the WebSocket clients
+// subscribed to the order, or to its customer, using the WebSocketEvent format.
 func (s *Store) BroadcastOrderUpdate(update OrderUpdate) {
-	event := WebSocketEvent{
-		OrderID:   update.OrderID,
-		Status:    update.Status,
-		Source:    update.Source,
-		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	var customerID *uuid.UUID
+	s.mu.RLock()
+	if order, exists := s.orders[update.OrderID]; exists {
+		customerID = order.CustomerID
 	}
-	message, err := json.Marshal(event)
-	if err != nil {
-		slog.Error("failed to marshal websocket event", "error", err)
-		return
+	s.mu.RUnlock()
+
+	event := WebSocketEvent{
+		OrderID:    update.OrderID,
+		CustomerID: customerID,
+		Status:     update.Status,
+		Source:     update.Source,
+		DriverID:   update.DriverID,
+		Location:   update.Location,
+		Reason:     update.Reason,
+		Timestamp:  time.Now().UTC().Format(time.RFC3339),
+
+		EstimatedReadyAt:    update.EstimatedReadyAt,
+		EstimatedDeliveryAt: update.EstimatedDeliveryAt,
 	}
-	s.hub.Broadcast(message)
+	s.hub.Broadcast(event)
 }
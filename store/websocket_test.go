@@ -3,6 +3,7 @@ package store
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -10,9 +11,43 @@ import (
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 )
 
+// subscribe sends a subscription message on the client's connection and waits until the hub has
+// applied it.
+func subscribe(t *testing.T, store *Store, conn *websocket.Conn, clientID string, msg SubscriptionMessage) {
+	t.Helper()
+	if err := conn.WriteJSON(msg); err != nil {
+		t.Fatalf("failed to send subscription: %v", err)
+	}
+
+	want := msg.Action == ActionSubscribe
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		store.hub.mu.RLock()
+		applied := false
+		if client, exists := store.hub.clients[clientID]; exists {
+			applied = msg.Channel != ChannelAll || client.all == want
+			for _, orderID := range msg.OrderIDs {
+				applied = applied && client.orders[orderID] == want
+			}
+			if msg.CustomerID != nil {
+				applied = applied && client.customers[*msg.CustomerID] == want
+			}
+		}
+		store.hub.mu.RUnlock()
+		if applied {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for client %s to %s", clientID, msg.Action)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
 // TestWebSocketConnection verifies that clients can connect via WebSocket.
 func TestWebSocketConnection(t *testing.T) {
 	store := NewStore()
@@ -71,6 +106,7 @@ func TestWebSocketReceivesOrderUpdates(t *testing.T) {
 
 	var createdOrder Order
 	json.NewDecoder(resp.Body).Decode(&createdOrder)
+	subscribe(t, store, conn, "update-client", SubscriptionMessage{Action: ActionSubscribe, OrderIDs: []uuid.UUID{createdOrder.OrderID}})
 
 	// Send an event to update the order
 	eventReq := OrderEvent{
@@ -188,6 +224,7 @@ func TestWebSocketBroadcastToSpecificClient(t *testing.T) {
 
 	var createdOrder Order
 	json.NewDecoder(resp.Body).Decode(&createdOrder)
+	subscribe(t, store, conn, "client-abc", SubscriptionMessage{Action: ActionSubscribe, OrderIDs: []uuid.UUID{createdOrder.OrderID}})
 
 	// Send an event
 	eventReq := OrderEvent{
@@ -228,6 +265,7 @@ func TestWebSocketBroadcastToSpecificClient(t *testing.T) {
 // TestMultipleWebSocketClients verifies that multiple clients can receive updates.
 func TestMultipleWebSocketClients(t *testing.T) {
 	store := NewStore()
+	store.SetAdminToken("secret")
 	router := chi.NewRouter()
 	router.Post("/order", store.HandleCreateOrder)
 	router.Post("/events", store.HandleEvent)
@@ -263,6 +301,10 @@ func TestMultipleWebSocketClients(t *testing.T) {
 	json.NewDecoder(resp.Body).Decode(&createdOrder)
 	resp.Body.Close()
 
+	// One client follows the order, the other every order
+	subscribe(t, store, conn1, "multi-client-1", SubscriptionMessage{Action: ActionSubscribe, OrderIDs: []uuid.UUID{createdOrder.OrderID}})
+	subscribe(t, store, conn2, "multi-client-2", SubscriptionMessage{Action: ActionSubscribe, Channel: ChannelAll, Token: "secret"})
+
 	// Send an event
 	eventReq := OrderEvent{
 		OrderID: createdOrder.OrderID,
@@ -295,3 +337,336 @@ func TestMultipleWebSocketClients(t *testing.T) {
 		t.Error("both clients should receive 'ready' status")
 	}
 }
+
+// TestWebSocketReceivesLocationUpdates verifies that delivery locations are forwarded to WebSocket clients.
+func TestWebSocketReceivesLocationUpdates(t *testing.T) {
+	store := NewStore()
+	router := chi.NewRouter()
+	router.Post("/events", store.HandleEvent)
+	router.Get("/ws", store.HandleWebSocket)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws?clientId=location-client"
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatalf("failed to connect to WebSocket: %v", err)
+	}
+	defer conn.Close()
+
+	order := &Order{OrderID: uuid.New(), OrderItems: []OrderItem{{PizzaType: "Margherita", Quantity: 1}}, OrderStatus: "COOKED"}
+	store.orders[order.OrderID] = order
+	subscribe(t, store, conn, "location-client", SubscriptionMessage{Action: ActionSubscribe, OrderIDs: []uuid.UUID{order.OrderID}})
+
+	location := Location{Lat: 41.3950, Lon: 2.1686, Heading: 90, ETASeconds: 3}
+	eventBody, _ := json.Marshal(OrderEvent{OrderID: order.OrderID, Status: "delivering 50%", Source: "delivery", DriverID: "driver-2", Location: &location})
+	resp, err := http.Post(server.URL+"/events", "application/json", bytes.NewReader(eventBody))
+	if err != nil {
+		t.Fatalf("failed to send event: %v", err)
+	}
+	defer resp.Body.Close()
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, message, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("failed to read WebSocket message: %v", err)
+	}
+
+	var event WebSocketEvent
+	if err := json.Unmarshal(message, &event); err != nil {
+		t.Fatalf("failed to unmarshal WebSocket message: %v", err)
+	}
+	if event.DriverID != "driver-2" {
+		t.Errorf("expected driverId 'driver-2', got '%s'", event.DriverID)
+	}
+	if event.Location == nil || *event.Location != location {
+		t.Errorf("expected location %+v, got %+v", location, event.Location)
+	}
+}
+
+// dialClient connects a WebSocket client with the given ID to the server.
+func dialClient(t *testing.T, server *httptest.Server, clientID string) *websocket.Conn {
+	t.Helper()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws?clientId="+clientID, nil)
+	if err != nil {
+		t.Fatalf("failed to connect %s: %v", clientID, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// receivedOrders reads the events a client receives until none arrive for a while and returns
+// the orders they were for.
+func receivedOrders(conn *websocket.Conn) []uuid.UUID {
+	var orders []uuid.UUID
+	for {
+		conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+		var event WebSocketEvent
+		if err := conn.ReadJSON(&event); err != nil {
+			return orders
+		}
+		orders = append(orders, event.OrderID)
+	}
+}
+
+// TestWebSocketRoutesEventsToSubscribers verifies that clients only receive the updates of the
+// orders and customers they subscribed to, and stop receiving them once they unsubscribe.
+func TestWebSocketRoutesEventsToSubscribers(t *testing.T) {
+	store := NewStore()
+	router := chi.NewRouter()
+	router.Post("/events", store.HandleEvent)
+	router.Get("/ws", store.HandleWebSocket)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	alice := uuid.New()
+	mine := &Order{OrderID: uuid.New(), OrderStatus: "pending"}
+	hers := &Order{OrderID: uuid.New(), OrderStatus: "pending", CustomerID: &alice}
+	other := &Order{OrderID: uuid.New(), OrderStatus: "pending"}
+	for _, order := range []*Order{mine, hers, other} {
+		store.orders[order.OrderID] = order
+	}
+
+	orderConn := dialClient(t, server, "order-client")
+	customerConn := dialClient(t, server, "customer-client")
+	idleConn := dialClient(t, server, "idle-client")
+	leftConn := dialClient(t, server, "left-client")
+	subscribe(t, store, orderConn, "order-client", SubscriptionMessage{Action: ActionSubscribe, OrderIDs: []uuid.UUID{mine.OrderID}})
+	subscribe(t, store, customerConn, "customer-client", SubscriptionMessage{Action: ActionSubscribe, CustomerID: &alice})
+	subscribe(t, store, leftConn, "left-client", SubscriptionMessage{Action: ActionSubscribe, OrderIDs: []uuid.UUID{mine.OrderID}})
+	subscribe(t, store, leftConn, "left-client", SubscriptionMessage{Action: ActionUnsubscribe, OrderIDs: []uuid.UUID{mine.OrderID}})
+
+	for _, order := range []*Order{mine, hers, other} {
+		postEvent(router, OrderEvent{OrderID: order.OrderID, Status: "cooking", Source: "kitchen"})
+	}
+
+	if got := receivedOrders(orderConn); len(got) != 1 || got[0] != mine.OrderID {
+		t.Errorf("expected only the subscribed order's update, got %v", got)
+	}
+	if got := receivedOrders(customerConn); len(got) != 1 || got[0] != hers.OrderID {
+		t.Errorf("expected only the customer's order update, got %v", got)
+	}
+	if got := receivedOrders(idleConn); len(got) != 0 {
+		t.Errorf("expected a client without subscriptions to receive nothing, got %v", got)
+	}
+	if got := receivedOrders(leftConn); len(got) != 0 {
+		t.Errorf("expected no updates after unsubscribing, got %v", got)
+	}
+}
+
+// TestWebSocketAdminChannelRequiresToken verifies that only clients with the admin token can
+// subscribe to the updates of every order.
+func TestWebSocketAdminChannelRequiresToken(t *testing.T) {
+	store := NewStore()
+	store.SetAdminToken("secret")
+	router := chi.NewRouter()
+	router.Post("/events", store.HandleEvent)
+	router.Get("/ws", store.HandleWebSocket)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	order := &Order{OrderID: uuid.New(), OrderStatus: "pending"}
+	store.orders[order.OrderID] = order
+
+	adminConn := dialClient(t, server, "admin-client")
+	snoopConn := dialClient(t, server, "snoop-client")
+	subscribe(t, store, adminConn, "admin-client", SubscriptionMessage{Action: ActionSubscribe, Channel: ChannelAll, Token: "secret"})
+	snoopConn.WriteJSON(SubscriptionMessage{Action: ActionSubscribe, Channel: ChannelAll, Token: "guess"})
+	// Subscribe the snooping client to another order so its refused message is known to be handled
+	subscribe(t, store, snoopConn, "snoop-client", SubscriptionMessage{Action: ActionSubscribe, OrderIDs: []uuid.UUID{uuid.New()}})
+
+	postEvent(router, OrderEvent{OrderID: order.OrderID, Status: "cooking", Source: "kitchen"})
+
+	if got := receivedOrders(adminConn); len(got) != 1 {
+		t.Errorf("expected the admin client to receive the update, got %v", got)
+	}
+	if got := receivedOrders(snoopConn); len(got) != 0 {
+		t.Errorf("expected a client without the token to receive nothing, got %v", got)
+	}
+
+	// Without a configured admin token nobody can subscribe to every order
+	store.SetAdminToken("")
+	openConn := dialClient(t, server, "open-client")
+	openConn.WriteJSON(SubscriptionMessage{Action: ActionSubscribe, Channel: ChannelAll})
+	subscribe(t, store, openConn, "open-client", SubscriptionMessage{Action: ActionSubscribe, OrderIDs: []uuid.UUID{uuid.New()}})
+
+	postEvent(router, OrderEvent{OrderID: order.OrderID, Status: "COOKED", Source: "kitchen"})
+
+	if got := receivedOrders(openConn); len(got) != 0 {
+		t.Errorf("expected no client to get every order without an admin token, got %v", got)
+	}
+}
+
+// TestWebSocketEvictsSlowClients verifies that broadcasting does not wait for clients, and that a
+// client whose queue is full is evicted while clients keeping up stay connected.
+func TestWebSocketEvictsSlowClients(t *testing.T) {
+	hub := NewWebSocketHub()
+	slow := &wsClient{send: make(chan []hubEvent, 1), all: true}
+	fast := &wsClient{send: make(chan []hubEvent, 2), all: true}
+	hub.clients["slow-client"] = slow
+	hub.clients["fast-client"] = fast
+
+	done := make(chan struct{})
+	go func() {
+		hub.Broadcast(WebSocketEvent{OrderID: uuid.New(), Status: "cooking"})
+		hub.Broadcast(WebSocketEvent{OrderID: uuid.New(), Status: "COOKED"})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected broadcasting not to wait for slow clients")
+	}
+
+	if hub.HasClient("slow-client") {
+		t.Error("expected the slow client to be evicted")
+	}
+	if len(slow.send) != 1 {
+		t.Errorf("expected the slow client to keep its queued message, got %d", len(slow.send))
+	}
+	<-slow.send
+	if _, open := <-slow.send; open {
+		t.Error("expected the slow client's queue to be closed")
+	}
+	if !hub.HasClient("fast-client") || len(fast.send) != 2 {
+		t.Errorf("expected the fast client to get both updates, got %d", len(fast.send))
+	}
+}
+
+// TestWebSocketKeepalive verifies that clients are pinged, stay connected while they answer with
+// pongs, and are disconnected once they stop answering.
+func TestWebSocketKeepalive(t *testing.T) {
+	store := NewStore()
+	store.hub.pingPeriod = 50 * time.Millisecond
+	store.hub.pongWait = 200 * time.Millisecond
+	router := chi.NewRouter()
+	router.Get("/ws", store.HandleWebSocket)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	// Pongs are only sent while the connection is read
+	pings := make(chan struct{}, 100)
+	liveConn := dialClient(t, server, "live-client")
+	liveConn.SetPingHandler(func(data string) error {
+		pings <- struct{}{}
+		return liveConn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+	})
+	go func() {
+		for {
+			if _, _, err := liveConn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+	dialClient(t, server, "silent-client")
+
+	time.Sleep(500 * time.Millisecond)
+	if len(pings) < 3 {
+		t.Errorf("expected the client to be pinged regularly, got %d pings", len(pings))
+	}
+	if !store.hub.HasClient("live-client") {
+		t.Error("expected a client answering pings to stay connected")
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for store.hub.HasClient("silent-client") {
+		if time.Now().After(deadline) {
+			t.Fatal("expected a client not answering pings to be disconnected")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// readEvents reads the given number of events from a client.
+func readEvents(t *testing.T, conn *websocket.Conn, count int) []WebSocketEvent {
+	t.Helper()
+	events := make([]WebSocketEvent, count)
+	for i := range events {
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		if err := conn.ReadJSON(&events[i]); err != nil {
+			t.Fatalf("failed to read event %d: %v", i+1, err)
+		}
+	}
+	return events
+}
+
+// TestWebSocketReplaysMissedEvents verifies that events are numbered, and that a client
+// reconnecting with the ID of the last event it received gets the events it missed for its
+// subscriptions before live updates, while new clients only get live updates.
+func TestWebSocketReplaysMissedEvents(t *testing.T) {
+	store := NewStore()
+	router := chi.NewRouter()
+	router.Post("/events", store.HandleEvent)
+	router.Get("/ws", store.HandleWebSocket)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	order := &Order{OrderID: uuid.New(), OrderStatus: "pending"}
+	other := &Order{OrderID: uuid.New(), OrderStatus: "pending"}
+	store.orders[order.OrderID] = order
+	store.orders[other.OrderID] = other
+	follow := SubscriptionMessage{Action: ActionSubscribe, OrderIDs: []uuid.UUID{order.OrderID}}
+
+	conn := dialClient(t, server, "phone")
+	subscribe(t, store, conn, "phone", follow)
+	postEvent(router, OrderEvent{OrderID: order.OrderID, Status: "prep 50%", Source: "kitchen"})
+	last := readEvents(t, conn, 1)[0]
+	conn.Close()
+
+	postEvent(router, OrderEvent{OrderID: order.OrderID, Status: "DONE", Source: "kitchen"})
+	postEvent(router, OrderEvent{OrderID: other.OrderID, Status: "prep 10%", Source: "kitchen"})
+	postEvent(router, OrderEvent{OrderID: order.OrderID, Status: "delivering 20%", Source: "delivery"})
+
+	reconnected, _, err := websocket.DefaultDialer.Dial(fmt.Sprintf("ws%s/ws?clientId=phone&lastEventId=%d", strings.TrimPrefix(server.URL, "http"), last.ID), nil)
+	if err != nil {
+		t.Fatalf("failed to reconnect: %v", err)
+	}
+	defer reconnected.Close()
+	fresh := dialClient(t, server, "laptop")
+	subscribe(t, store, reconnected, "phone", follow)
+	subscribe(t, store, fresh, "laptop", follow)
+	postEvent(router, OrderEvent{OrderID: order.OrderID, Status: "DELIVERED", Source: "delivery"})
+
+	var statuses []string
+	var ids []uint64
+	for _, event := range readEvents(t, reconnected, 3) {
+		statuses = append(statuses, event.Status)
+		ids = append(ids, event.ID)
+	}
+	if strings.Join(statuses, ",") != "COOKED,delivering 20%,DELIVERED" {
+		t.Errorf("expected the missed events before the live one, got %v", statuses)
+	}
+	if ids[0] <= last.ID || ids[1] <= ids[0] || ids[2] <= ids[1] {
+		t.Errorf("expected increasing event IDs after %d, got %v", last.ID, ids)
+	}
+	if got := readEvents(t, fresh, 1)[0]; got.Status != "DELIVERED" {
+		t.Errorf("expected a new client to only get live updates, got '%s'", got.Status)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ws?clientId=phone&lastEventId=latest", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 Bad Request for an invalid lastEventId, got %d", rec.Code)
+	}
+}
+
+// TestWebSocketReplayIsBounded verifies that only the most recent events are kept for replay.
+func TestWebSocketReplayIsBounded(t *testing.T) {
+	hub := NewWebSocketHub()
+	hub.history = make([]hubEvent, 2)
+	orderID := uuid.New()
+	for _, status := range []string{"prep 50%", "COOKED", "DELIVERED"} {
+		hub.Broadcast(WebSocketEvent{OrderID: orderID, Status: status})
+	}
+
+	client := newWSClient(nil, 1)
+	client.replay = true
+	hub.clients["phone"] = client
+	hub.Subscribe("phone", SubscriptionMessage{Action: ActionSubscribe, OrderIDs: []uuid.UUID{orderID}})
+
+	batch := <-client.send
+	if len(batch) != 2 || batch[0].event.ID != 2 || batch[1].event.Status != "DELIVERED" {
+		t.Errorf("expected the two most recent events, got %+v", batch)
+	}
+}